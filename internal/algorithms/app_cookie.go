@@ -0,0 +1,192 @@
+package algorithms
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+const defaultAppCookieMaxEntries = 10000
+
+// appCookie pins a client to a target by a session cookie the application
+// issues itself, such as JSESSIONID. The cookie to target mapping is learned
+// from the Set-Cookie headers of the responses.
+type appCookie struct {
+	algorithm  alg.Algorithm
+	targets    map[string]*lb.Target
+	cookieName string
+	sessions   *lru[*lb.Target]
+}
+
+type NewAppCookieOptions struct {
+	CookieName string
+	// MaxEntries bounds the sessions remembered at once, the least recently
+	// used one is forgotten first.
+	MaxEntries int
+	// Duration is how long a session is remembered after its last request.
+	Duration time.Duration
+}
+
+// NewAppCookie wraps the algorithm built by newAlgorithm, which gets a proxy
+// factory whose proxies learn the sessions of the target they reach.
+func NewAppCookie(targets []*lb.Target, proxyFactory proxy.ProxyFactory, newAlgorithm func(proxy.ProxyFactory) (alg.Algorithm, error), opts NewAppCookieOptions) (*appCookie, error) {
+	maxEntries := opts.MaxEntries
+
+	if maxEntries <= 0 {
+		maxEntries = defaultAppCookieMaxEntries
+	}
+
+	a := &appCookie{
+		targets:    make(map[string]*lb.Target, len(targets)),
+		cookieName: opts.CookieName,
+		sessions:   newLRU[*lb.Target](maxEntries, opts.Duration),
+	}
+
+	for _, target := range targets {
+		a.targets[net.JoinHostPort(target.Host, strconv.Itoa(target.Port))] = target
+	}
+
+	algorithm, err := newAlgorithm(&appCookieProxyFactory{proxyFactory: proxyFactory, sticky: a})
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.algorithm = algorithm
+
+	return a, nil
+}
+
+// learn records the sessions the target issued in header in response to req,
+// and forgets the ones it expired, so a logged out session is balanced again.
+func (a *appCookie) learn(header http.Header, req *http.Request, target *lb.Target) {
+	response := http.Response{Header: header}
+
+	for _, cookie := range response.Cookies() {
+		if cookie.Name != a.cookieName {
+			continue
+		}
+
+		// net/http reports a Max-Age of 0 or less as a negative MaxAge. The
+		// cookie deleting a session rarely repeats its value, so the session
+		// of the request is forgotten as well.
+		if cookie.Value == "" || cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && !cookie.Expires.After(time.Now())) {
+			a.sessions.Delete(cookie.Value)
+
+			if session, err := req.Cookie(a.cookieName); err == nil {
+				a.sessions.Delete(session.Value)
+			}
+
+			continue
+		}
+
+		a.sessions.Set(cookie.Value, target)
+	}
+}
+
+func (a *appCookie) stickyTarget(req *http.Request) *lb.Target {
+	cookie, err := req.Cookie(a.cookieName)
+
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+
+	target, ok := a.sessions.Get(cookie.Value)
+
+	if !ok || !target.IsHealthy() {
+		return nil
+	}
+
+	return target
+}
+
+// Handle sends the request through the wrapped algorithm, pinned to the
+// target of its session if any, so the algorithm keeps accounting for it.
+func (a *appCookie) Handle(w http.ResponseWriter, req *http.Request) error {
+	if target := a.stickyTarget(req); target != nil {
+		req = withPinnedTarget(req, target)
+	}
+
+	return a.algorithm.Handle(w, req)
+}
+
+func (a *appCookie) Unwrap() []alg.Algorithm {
+	return []alg.Algorithm{a.algorithm}
+}
+
+// HandleConn has no session to follow, a connection never carries the
+// application cookie.
+func (a *appCookie) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	return delegateConn(a.algorithm, conn, proxy)
+}
+
+type appCookieProxyFactory struct {
+	proxyFactory proxy.ProxyFactory
+	sticky       *appCookie
+}
+
+func (f *appCookieProxyFactory) Create(host string, port int) proxy.Proxy {
+	return &appCookieProxy{
+		proxy:  f.proxyFactory.Create(host, port),
+		sticky: f.sticky,
+		target: f.sticky.targets[net.JoinHostPort(host, strconv.Itoa(port))],
+	}
+}
+
+type appCookieProxy struct {
+	proxy  proxy.Proxy
+	sticky *appCookie
+	target *lb.Target
+}
+
+func (p *appCookieProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rw := &appCookieResponseWriter{ResponseWriter: w, proxy: p, req: req}
+	p.proxy.ServeHTTP(rw, req)
+
+	// A handler that writes nothing leaves the headers to net/http.
+	if !rw.wroteHeader && p.target != nil {
+		p.sticky.learn(w.Header(), req, p.target)
+	}
+}
+
+// appCookieResponseWriter learns the sessions before the headers are sent,
+// so a request racing the response already finds its target.
+type appCookieResponseWriter struct {
+	http.ResponseWriter
+	proxy       *appCookieProxy
+	req         *http.Request
+	wroteHeader bool
+}
+
+func (w *appCookieResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader && w.proxy.target != nil {
+		w.proxy.sticky.learn(w.Header(), w.req, w.proxy.target)
+	}
+
+	// Informational responses are followed by the final one, which may
+	// carry the cookie as well.
+	if statusCode >= http.StatusOK || statusCode == http.StatusSwitchingProtocols {
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *appCookieResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the Flusher and Hijacker of the
+// underlying writer.
+func (w *appCookieResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
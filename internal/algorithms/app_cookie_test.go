@@ -0,0 +1,169 @@
+package algorithms
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestAppCookie(targets []*lb.Target, proxyFactory proxy.ProxyFactory, maxEntries int) (*appCookie, *roundRobin) {
+	var rr *roundRobin
+
+	sticky, _ := NewAppCookie(targets, proxyFactory, func(proxyFactory proxy.ProxyFactory) (alg.Algorithm, error) {
+		rr = NewRoundRobin(targets, proxyFactory)
+		return rr, nil
+	}, NewAppCookieOptions{
+		CookieName: "JSESSIONID",
+		MaxEntries: maxEntries,
+		Duration:   time.Hour,
+	})
+
+	return sticky, rr
+}
+
+func sessionRequest(session string) *http.Request {
+	r := httptest.NewRequest("GET", "http://localhost/", nil)
+
+	if session != "" {
+		r.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: session})
+	}
+
+	return r
+}
+
+func TestAppCookie_Handle(t *testing.T) {
+	t.Run("Should learn the session issued by a target and send it back there", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		first := &MockedProxy{}
+		second := &MockedProxy{}
+
+		sticky, rr := newTestAppCookie(getTargets(), proxyFactory, 0)
+
+		proxyFactory.On("Create", "localhost", 8080).Return(first)
+		proxyFactory.On("Create", "localhost", 8081).Return(second)
+
+		first.On("ServeHTTP", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			w := args.Get(0).(http.ResponseWriter)
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "abc"})
+			w.WriteHeader(http.StatusOK)
+		}).Return()
+		second.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+		assert.Nil(t, sticky.Handle(httptest.NewRecorder(), sessionRequest("")))
+
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, sticky.Handle(httptest.NewRecorder(), sessionRequest("abc")))
+		}
+
+		assert.Nil(t, sticky.Handle(httptest.NewRecorder(), sessionRequest("unknown")))
+
+		assert.Equal(t, int64(0), rr.current.Load())
+		first.AssertNumberOfCalls(t, "ServeHTTP", 4)
+		second.AssertNumberOfCalls(t, "ServeHTTP", 1)
+	})
+
+	t.Run("Should fall back to the wrapped algorithm when the session target is unhealthy", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		targets := getTargets()
+		sticky, _ := newTestAppCookie(targets, proxyFactory, 0)
+		sticky.sessions.Set("abc", targets[0])
+		targets[0].SetHealthy(false)
+
+		proxyFactory.On("Create", "localhost", 8081).Return(proxy)
+		proxy.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+		assert.Nil(t, sticky.Handle(httptest.NewRecorder(), sessionRequest("abc")))
+
+		proxyFactory.AssertExpectations(t)
+	})
+
+	t.Run("Should forget a session the target clears", func(t *testing.T) {
+		targets := getTargets()
+		sticky, _ := newTestAppCookie(targets, &MockedProxyFactory{}, 0)
+		sticky.sessions.Set("abc", targets[0])
+
+		header := http.Header{}
+		header.Add("Set-Cookie", "JSESSIONID=abc; Max-Age=0")
+
+		sticky.learn(header, sessionRequest("abc"), targets[0])
+
+		_, ok := sticky.sessions.Get("abc")
+		assert.False(t, ok)
+	})
+
+	t.Run("Should forget the session of a request whose response expires the cookie", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		upstream := &MockedProxy{}
+
+		targets := getTargets()
+		sticky, _ := newTestAppCookie(targets, proxyFactory, 0)
+		sticky.sessions.Set("abc", targets[1])
+		sticky.sessions.Set("def", targets[1])
+
+		proxyFactory.On("Create", "localhost", 8081).Return(upstream)
+		upstream.On("ServeHTTP", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			w := args.Get(0).(http.ResponseWriter)
+			w.Header().Add("Set-Cookie", "JSESSIONID=deleted; Expires=Thu, 01 Jan 1970 00:00:00 GMT")
+			w.WriteHeader(http.StatusOK)
+		}).Return()
+
+		assert.Nil(t, sticky.Handle(httptest.NewRecorder(), sessionRequest("abc")))
+
+		_, ok := sticky.sessions.Get("abc")
+		assert.False(t, ok)
+
+		_, ok = sticky.sessions.Get("def")
+		assert.True(t, ok)
+		proxyFactory.AssertExpectations(t)
+	})
+}
+
+func TestLRU(t *testing.T) {
+	t.Run("Should evict the least recently used entry when full", func(t *testing.T) {
+		cache := newLRU[int](3, time.Hour)
+
+		for i := 0; i < 3; i++ {
+			cache.Set(fmt.Sprintf("key-%d", i), i)
+		}
+
+		cache.Get("key-0")
+		cache.Set("key-3", 3)
+
+		_, ok := cache.Get("key-1")
+
+		assert.False(t, ok)
+		assert.Equal(t, 3, cache.Len())
+
+		value, ok := cache.Get("key-0")
+
+		assert.True(t, ok)
+		assert.Equal(t, 0, value)
+	})
+
+	t.Run("Should forget entries once they expire", func(t *testing.T) {
+		now := time.Now()
+
+		cache := newLRU[int](3, time.Minute)
+		cache.now = func() time.Time { return now }
+
+		cache.Set("key", 1)
+
+		now = now.Add(30 * time.Second)
+		_, ok := cache.Get("key")
+		assert.True(t, ok)
+
+		now = now.Add(61 * time.Second)
+		_, ok = cache.Get("key")
+		assert.False(t, ok)
+	})
+}
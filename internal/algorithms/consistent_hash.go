@@ -0,0 +1,208 @@
+package algorithms
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sort"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+const defaultVirtualNodes = 160
+
+// The sources a consistent-hash key can be read from.
+const (
+	HashKeyIp     = "ip"
+	HashKeyHeader = "header"
+	HashKeyCookie = "cookie"
+	HashKeyQuery  = "query"
+)
+
+// HashKey says which part of a request is hashed. Requests that lack the
+// header, cookie or query parameter named by Name are hashed on the client
+// ip instead.
+type HashKey struct {
+	Source string
+	Name   string
+}
+
+func (k HashKey) value(req *http.Request) string {
+	switch k.Source {
+	case HashKeyHeader:
+		if value := req.Header.Get(k.Name); value != "" {
+			return value
+		}
+	case HashKeyCookie:
+		if cookie, err := req.Cookie(k.Name); err == nil && cookie.Value != "" {
+			return cookie.Value
+		}
+	case HashKeyQuery:
+		if value := req.URL.Query().Get(k.Name); value != "" {
+			return value
+		}
+	}
+
+	return hostOf(req.RemoteAddr)
+}
+
+// connKey is the key a connection is hashed on whatever the configured key:
+// the client ip is all a connection has.
+func connKey(conn net.Conn) string {
+	return hostOf(conn.RemoteAddr().String())
+}
+
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
+
+// hashString hashes s with FNV-1a, then mixes the result with the murmur3
+// finalizer since FNV alone spreads similar strings poorly over the ring.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	return x
+}
+
+type ringPoint struct {
+	hash   uint64
+	target *lb.Target
+}
+
+type consistentHash struct {
+	ring         []ringPoint
+	targets      []*lb.Target
+	key          HashKey
+	proxyFactory proxy.ProxyFactory
+}
+
+type NewConsistentHashOptions struct {
+	Key HashKey
+	// VirtualNodes is the number of points each target gets on the ring per
+	// unit of weight.
+	VirtualNodes int
+}
+
+func NewConsistentHash(targets []*lb.Target, proxyFactory proxy.ProxyFactory, opts NewConsistentHashOptions) *consistentHash {
+	virtualNodes := opts.VirtualNodes
+
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+
+	ring := make([]ringPoint, 0, len(targets)*virtualNodes)
+
+	for _, target := range targets {
+		for i := 0; i < virtualNodes*target.EffectiveWeight(); i++ {
+			ring = append(ring, ringPoint{
+				hash:   hashString(fmt.Sprintf("%s:%d-%d", target.Host, target.Port, i)),
+				target: target,
+			})
+		}
+	}
+
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+
+	return &consistentHash{
+		ring:         ring,
+		targets:      targets,
+		key:          opts.Key,
+		proxyFactory: proxyFactory,
+	}
+}
+
+// next walks the ring clockwise from the point of key, so a key whose target
+// is unhealthy moves to the following target while every other key stays.
+// With upgrade, targets at their cap of upgraded connections are skipped the
+// same way and the returned target holds a slot for the upgrade.
+func (c *consistentHash) next(key string, upgrade bool) (*lb.Target, error) {
+	if len(c.ring) == 0 {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
+	hash := hashString(key)
+	start := sort.Search(len(c.ring), func(i int) bool {
+		return c.ring[i].hash >= hash
+	})
+
+	visited := make(map[*lb.Target]bool, len(c.targets))
+	err := errs.ErrNoHealthyTargets
+
+	for i := 0; i < len(c.ring) && len(visited) < len(c.targets); i++ {
+		target := c.ring[(start+i)%len(c.ring)].target
+
+		if visited[target] {
+			continue
+		}
+
+		visited[target] = true
+
+		if !target.IsHealthy() {
+			continue
+		}
+
+		if !upgrade || target.AcquireUpgradedConn() {
+			return target, nil
+		}
+
+		err = errs.ErrUpgradeLimit
+	}
+
+	return nil, err
+}
+
+func (c *consistentHash) Handle(w http.ResponseWriter, req *http.Request) error {
+	upgrade := isUpgrade(req)
+	currentTarget, ok := pinnedTarget(req, c.targets, itself)
+
+	// A pinned target without room for an upgrade leaves it to the ring.
+	if !ok || (upgrade && !currentTarget.AcquireUpgradedConn()) {
+		var err error
+
+		currentTarget, err = c.next(c.key.value(req), upgrade)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if upgrade {
+		return serveUpgrade(w, req, currentTarget, c.proxyFactory)
+	}
+
+	proxy := c.proxyFactory.Create(currentTarget.Host, currentTarget.Port)
+	proxy.ServeHTTP(w, req)
+
+	return nil
+}
+
+func (c *consistentHash) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	currentTarget, err := c.next(connKey(conn), false)
+
+	if err != nil {
+		return err
+	}
+
+	return proxy.ServeConn(conn, currentTarget.Host, currentTarget.Port)
+}
@@ -0,0 +1,146 @@
+package algorithms
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+	"github.com/stretchr/testify/assert"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+func buildHashTargets(n int) []*lb.Target {
+	targets := make([]*lb.Target, n)
+
+	for i := range targets {
+		targets[i] = &lb.Target{Host: "10.0.0.1", Port: 8000 + i}
+	}
+
+	return healthyTargets(targets)
+}
+
+func assignKeys(t *testing.T, ch *consistentHash, keys int) map[string]int {
+	assignments := make(map[string]int, keys)
+
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("client-%d", i)
+		target, err := ch.next(key, false)
+
+		assert.Nil(t, err)
+		assignments[key] = target.Port
+	}
+
+	return assignments
+}
+
+func TestConsistentHash_Handle(t *testing.T) {
+	t.Run("Should send the same key to the same target", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		ch := NewConsistentHash(buildHashTargets(5), proxyFactory, NewConsistentHashOptions{
+			Key: HashKey{Source: HashKeyHeader, Name: "X-User"},
+		})
+
+		expected, err := ch.next("user-42", false)
+		assert.Nil(t, err)
+
+		proxyFactory.On("Create", expected.Host, expected.Port).Return(proxy)
+
+		for i := 0; i < 3; i++ {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "http://localhost/", nil)
+			r.RemoteAddr = fmt.Sprintf("192.168.0.%d:5000", i)
+			r.Header.Set("X-User", "user-42")
+
+			proxy.On("ServeHTTP", w, r).Return()
+
+			assert.Nil(t, ch.Handle(w, r))
+		}
+
+		proxyFactory.AssertNumberOfCalls(t, "Create", 3)
+	})
+
+	t.Run("Should spread keys evenly across targets", func(t *testing.T) {
+		ch := NewConsistentHash(buildHashTargets(4), &MockedProxyFactory{}, NewConsistentHashOptions{})
+
+		counts := map[int]int{}
+
+		for _, port := range assignKeys(t, ch, 10000) {
+			counts[port]++
+		}
+
+		for _, count := range counts {
+			assert.InDelta(t, 2500, count, 500)
+		}
+	})
+
+	t.Run("Should only remap the keys of a removed target", func(t *testing.T) {
+		targets := buildHashTargets(10)
+
+		before := assignKeys(t, NewConsistentHash(targets, &MockedProxyFactory{}, NewConsistentHashOptions{}), 10000)
+		after := assignKeys(t, NewConsistentHash(targets[:9], &MockedProxyFactory{}, NewConsistentHashOptions{}), 10000)
+
+		moved := 0
+
+		for key, port := range before {
+			if after[key] != port {
+				moved++
+				assert.Equal(t, targets[9].Port, port)
+			}
+		}
+
+		assert.InDelta(t, 1000, moved, 300)
+	})
+
+	t.Run("Should walk the ring past unhealthy targets", func(t *testing.T) {
+		targets := buildHashTargets(5)
+		ch := NewConsistentHash(targets, &MockedProxyFactory{}, NewConsistentHashOptions{})
+
+		before := assignKeys(t, ch, 1000)
+		targets[2].SetHealthy(false)
+		after := assignKeys(t, ch, 1000)
+
+		for key, port := range before {
+			if port == targets[2].Port {
+				assert.NotEqual(t, targets[2].Port, after[key])
+				continue
+			}
+
+			assert.Equal(t, port, after[key])
+		}
+	})
+
+	t.Run("Should return an error when no target is healthy", func(t *testing.T) {
+		targets := buildHashTargets(2)
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(false)
+
+		ch := NewConsistentHash(targets, &MockedProxyFactory{}, NewConsistentHashOptions{})
+
+		_, err := ch.next("client", false)
+
+		assert.Equal(t, errs.ErrNoHealthyTargets, err)
+	})
+}
+
+func TestHashKey(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://localhost/?tenant=acme", nil)
+	r.RemoteAddr = "10.1.2.3:51234"
+	r.Header.Set("X-User", "user-42")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	t.Run("Should read the key from the configured source", func(t *testing.T) {
+		assert.Equal(t, "10.1.2.3", HashKey{Source: HashKeyIp}.value(r))
+		assert.Equal(t, "user-42", HashKey{Source: HashKeyHeader, Name: "X-User"}.value(r))
+		assert.Equal(t, "abc", HashKey{Source: HashKeyCookie, Name: "session"}.value(r))
+		assert.Equal(t, "acme", HashKey{Source: HashKeyQuery, Name: "tenant"}.value(r))
+	})
+
+	t.Run("Should fall back to the client ip when the key is missing", func(t *testing.T) {
+		assert.Equal(t, "10.1.2.3", HashKey{Source: HashKeyHeader, Name: "X-Missing"}.value(r))
+	})
+}
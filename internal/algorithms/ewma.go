@@ -0,0 +1,96 @@
+package algorithms
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const defaultEwmaDecay = 10 * time.Second
+
+// ewma is an exponentially weighted moving average over time: a sample
+// observed decay ago weighs 1/e of a sample observed now, however many
+// samples came in between.
+//
+// A peak ewma jumps straight to any sample above its value and only decays
+// afterwards, so a target that slows down is avoided at once while one that
+// recovers wins traffic back gradually. Its value also decays while no
+// sample comes in, so a target penalised once is eventually tried again.
+type ewma struct {
+	value float64
+	stamp time.Time
+	decay time.Duration
+	peak  bool
+	mux   sync.Mutex
+}
+
+func newEwma(decay time.Duration) *ewma {
+	if decay <= 0 {
+		decay = defaultEwmaDecay
+	}
+
+	return &ewma{
+		decay: decay,
+	}
+}
+
+func newPeakEwma(decay time.Duration) *ewma {
+	e := newEwma(decay)
+	e.peak = true
+
+	return e
+}
+
+// weight returns how much the value kept since the last sample still
+// counts at now.
+func (e *ewma) weight(now time.Time) float64 {
+	elapsed := now.Sub(e.stamp)
+
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	return math.Exp(-float64(elapsed) / float64(e.decay))
+}
+
+func (e *ewma) observe(sample float64, now time.Time) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if e.stamp.IsZero() {
+		e.value = sample
+		e.stamp = now
+
+		return
+	}
+
+	w := e.weight(now)
+	value := e.value
+
+	// The peak decays while no sample comes in, so the sample is compared
+	// with what the peak is worth now rather than when it was last set.
+	if e.peak {
+		value *= w
+
+		if sample > value {
+			e.value = sample
+			e.stamp = now
+
+			return
+		}
+	}
+
+	e.value = value*w + sample*(1-w)
+	e.stamp = now
+}
+
+func (e *ewma) get(now time.Time) float64 {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if e.peak && !e.stamp.IsZero() {
+		return e.value * e.weight(now)
+	}
+
+	return e.value
+}
@@ -0,0 +1,127 @@
+package algorithms
+
+import (
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+type leastConnectionsTarget struct {
+	*lb.Target
+	inFlight atomic.Int64
+}
+
+func (t *leastConnectionsTarget) lbTarget() *lb.Target {
+	return t.Target
+}
+
+type leastConnections struct {
+	targets      []*leastConnectionsTarget
+	proxyFactory proxy.ProxyFactory
+	// offset rotates where the scan for the least loaded target starts, so
+	// that targets tied on in-flight requests take turns.
+	offset atomic.Int64
+}
+
+func NewLeastConnections(targets []*lb.Target, proxyFactory proxy.ProxyFactory) *leastConnections {
+	lcTargets := make([]*leastConnectionsTarget, len(targets))
+
+	for i, target := range targets {
+		lcTargets[i] = &leastConnectionsTarget{Target: target}
+	}
+
+	return &leastConnections{
+		targets:      lcTargets,
+		proxyFactory: proxyFactory,
+	}
+}
+
+func (l *leastConnections) next() (*leastConnectionsTarget, error) {
+	numTargets := int64(len(l.targets))
+
+	if numTargets == 0 {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
+	start := (l.offset.Add(1) - 1) % numTargets
+
+	var selected *leastConnectionsTarget
+	var selectedLoad float64
+
+	now := time.Now()
+
+	for i := int64(0); i < numTargets; i++ {
+		target := l.targets[(start+i)%numTargets]
+
+		if !target.IsHealthy() {
+			continue
+		}
+
+		// Counting the request being placed keeps weights meaningful when no
+		// target has requests in flight, as with a target ramping up.
+		load := float64(target.inFlight.Load()+1) / target.SlowStartWeight(now)
+
+		if selected == nil || load < selectedLoad {
+			selected = target
+			selectedLoad = load
+		}
+	}
+
+	if selected == nil {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
+	return selected, nil
+}
+
+func (l *leastConnections) Handle(w http.ResponseWriter, req *http.Request) error {
+	currentTarget, err := pickPinned(req, l.targets, (*leastConnectionsTarget).lbTarget, l.next)
+
+	if err != nil {
+		return err
+	}
+
+	upgrade := isUpgrade(req)
+
+	if upgrade {
+		currentTarget, err = acquireUpgrade(l.targets, currentTarget)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// The deferred decrement also runs when the proxy panics, for instance
+	// with http.ErrAbortHandler when the client goes away.
+	currentTarget.inFlight.Add(1)
+	defer currentTarget.inFlight.Add(-1)
+
+	if upgrade {
+		return serveUpgrade(w, req, currentTarget.Target, l.proxyFactory)
+	}
+
+	proxy := l.proxyFactory.Create(currentTarget.Host, currentTarget.Port)
+	proxy.ServeHTTP(w, req)
+
+	return nil
+}
+
+func (l *leastConnections) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	currentTarget, err := l.next()
+
+	if err != nil {
+		return err
+	}
+
+	currentTarget.inFlight.Add(1)
+	defer currentTarget.inFlight.Add(-1)
+
+	return proxy.ServeConn(conn, currentTarget.Host, currentTarget.Port)
+}
@@ -0,0 +1,116 @@
+package algorithms
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+func TestLeastConnections_Handle(t *testing.T) {
+	t.Run("Should call the target with the fewest in-flight requests", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		lc := NewLeastConnections(getTargets(), proxyFactory)
+		lc.targets[0].inFlight.Store(3)
+		lc.targets[1].inFlight.Store(1)
+
+		proxyFactory.On("Create", "localhost", 8081).Return(proxy)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost", nil)
+
+		proxy.On("ServeHTTP", w, r).Return()
+
+		err := lc.Handle(w, r)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), lc.targets[1].inFlight.Load())
+		proxyFactory.AssertExpectations(t)
+	})
+
+	t.Run("Should count the request as in-flight while it is proxied", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		lc := NewLeastConnections(getTargets(), proxyFactory)
+
+		proxyFactory.On("Create", "localhost", 8080).Return(proxy)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost", nil)
+
+		var inFlight int64
+		proxy.On("ServeHTTP", w, r).Run(func(_ mock.Arguments) {
+			inFlight = lc.targets[0].inFlight.Load()
+		}).Return()
+
+		assert.Nil(t, lc.Handle(w, r))
+		assert.Equal(t, int64(1), inFlight)
+		assert.Equal(t, int64(0), lc.targets[0].inFlight.Load())
+	})
+
+	t.Run("Should release the in-flight request when the proxy panics", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		lc := NewLeastConnections(getTargets(), proxyFactory)
+
+		proxyFactory.On("Create", "localhost", 8080).Return(proxy)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost", nil)
+
+		proxy.On("ServeHTTP", w, r).Run(func(_ mock.Arguments) {
+			panic(http.ErrAbortHandler)
+		}).Return()
+
+		assert.Panics(t, func() { lc.Handle(w, r) })
+		assert.Equal(t, int64(0), lc.targets[0].inFlight.Load())
+	})
+
+	t.Run("Should take turns between targets tied on in-flight requests", func(t *testing.T) {
+		lc := NewLeastConnections(getTargets(), &MockedProxyFactory{})
+
+		counts := map[int]int{}
+
+		for i := 0; i < 10; i++ {
+			target, err := lc.next()
+
+			assert.Nil(t, err)
+			counts[target.Port]++
+		}
+
+		assert.Equal(t, map[int]int{8080: 5, 8081: 5}, counts)
+	})
+
+	t.Run("Should skip unhealthy targets", func(t *testing.T) {
+		targets := getTargets()
+		targets[0].SetHealthy(false)
+
+		lc := NewLeastConnections(targets, &MockedProxyFactory{})
+		lc.targets[1].inFlight.Store(10)
+
+		target, err := lc.next()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 8081, target.Port)
+	})
+
+	t.Run("Should return an error when no target is healthy", func(t *testing.T) {
+		targets := getTargets()
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(false)
+
+		lc := NewLeastConnections(targets, &MockedProxyFactory{})
+
+		_, err := lc.next()
+
+		assert.Equal(t, errs.ErrNoHealthyTargets, err)
+	})
+}
@@ -1,6 +1,7 @@
 package algorithms
 
 import (
+	"net"
 	"net/http"
 	"sort"
 	"sync"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
 	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
 
 	errs "github.com/joaosczip/go-lb/internal/errors"
@@ -41,24 +43,37 @@ func (t *timedResponseWriter) Write(b []byte) (int, error) {
 	return t.ResponseWriter.Write(b)
 }
 
+// Unwrap lets http.ResponseController reach the Flusher of the underlying
+// writer.
+func (t *timedResponseWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
 type leastResponseTimeTarget struct {
 	*lb.Target
+	// avgResponseTime caches the latency average in nanoseconds, so sorting
+	// the targets reads a single atomic per target.
 	avgResponseTime     atomic.Int64
-	requestCount        atomic.Int64
 	consecutiveRequests atomic.Int64
+	latency             *ewma
 }
 
-func newLeastResponseTimeTarget(target *lb.Target) *leastResponseTimeTarget {
+func (t *leastResponseTimeTarget) lbTarget() *lb.Target {
+	return t.Target
+}
+
+func newLeastResponseTimeTarget(target *lb.Target, decay time.Duration) *leastResponseTimeTarget {
 	return &leastResponseTimeTarget{
-		Target: target,
+		Target:  target,
+		latency: newEwma(decay),
 	}
 }
 
 func (l *leastResponseTimeTarget) setAvgResponseTime(responseTime time.Duration) {
-	l.requestCount.Add(1)
-	l.avgResponseTime.Store(
-		(l.avgResponseTime.Load() + responseTime.Nanoseconds()) / l.requestCount.Load(),
-	)
+	now := time.Now()
+
+	l.latency.observe(float64(responseTime.Nanoseconds()), now)
+	l.avgResponseTime.Store(int64(l.latency.get(now)))
 }
 
 type leastResponseTime struct {
@@ -67,17 +82,22 @@ type leastResponseTime struct {
 	maxConsecutiveRequests int64
 	requestsCount          atomic.Int64
 	mux                    sync.RWMutex
+	// randIntn draws the turns given away by ramping targets, from the
+	// global source when nil.
+	randIntn func(int) int
 }
 
 type NewLeastResponseTimeOptions struct {
 	MaxConsecutiveRequests int64
+	// Decay is how fast the response time average forgets older requests.
+	Decay time.Duration
 }
 
 func NewLeastResponseTime(targets []*lb.Target, proxyFactory proxy.ProxyFactory, opts NewLeastResponseTimeOptions) *leastResponseTime {
 	lrtTargets := make([]*leastResponseTimeTarget, len(targets))
 
 	for i, target := range targets {
-		lrtTargets[i] = newLeastResponseTimeTarget(target)
+		lrtTargets[i] = newLeastResponseTimeTarget(target, opts.Decay)
 	}
 
 	return &leastResponseTime{
@@ -96,31 +116,79 @@ func (l *leastResponseTime) targetsSortedByAvgResponseTime() []*leastResponseTim
 	targetsCopy := make([]*leastResponseTimeTarget, len(l.targets))
 	copy(targetsCopy, l.targets)
 
+	// Weights divide the average: a target twice as heavy may answer twice
+	// as slowly before it is considered worse.
 	sort.Slice(targetsCopy, func(i, j int) bool {
-		return targetsCopy[i].avgResponseTime.Load() < targetsCopy[j].avgResponseTime.Load()
+		return targetsCopy[i].avgResponseTime.Load()*int64(targetsCopy[j].EffectiveWeight()) <
+			targetsCopy[j].avgResponseTime.Load()*int64(targetsCopy[i].EffectiveWeight())
 	})
 
 	return targetsCopy
 }
 
-func (l *leastResponseTime) Handle(w http.ResponseWriter, req *http.Request) error {
+func (l *leastResponseTime) next() (*leastResponseTimeTarget, error) {
 	sortedTargets := l.targets
-	currentTarget := sortedTargets[0]
+
+	if len(sortedTargets) == 0 {
+		return nil, errs.ErrNoHealthyTargets
+	}
 
 	if l.requestsCount.Load() > 0 {
-		sortedTargets := l.targetsSortedByAvgResponseTime()
-		currentTarget = sortedTargets[0]
+		sortedTargets = l.targetsSortedByAvgResponseTime()
+	}
+
+	now := time.Now()
+
+	var givenAway *leastResponseTimeTarget
+
+	for _, target := range sortedTargets {
+		if !target.IsHealthy() || target.consecutiveRequests.Load() > l.maxConsecutiveRequests {
+			target.consecutiveRequests.Store(0)
+			continue
+		}
+
+		// As in round robin, a target ramping up after becoming healthy
+		// gives its turn away with the probability of the share of its
+		// weight it does not have yet. A ramping target with no average
+		// yet would otherwise take every request.
+		if !keepsTurn(target.SlowStartFactor(now), l.randIntn) {
+			if givenAway == nil {
+				givenAway = target
+			}
+
+			continue
+		}
+
+		return target, nil
+	}
+
+	if givenAway != nil {
+		return givenAway, nil
 	}
 
-	nextIdx := 1
-	for !currentTarget.IsHealthy() || currentTarget.consecutiveRequests.Load() > l.maxConsecutiveRequests {
-		if nextIdx == len(sortedTargets) {
-			return errs.ErrNoHealthyTargets
+	return nil, errs.ErrNoHealthyTargets
+}
+
+func (l *leastResponseTime) Handle(w http.ResponseWriter, req *http.Request) error {
+	currentTarget, err := pickPinned(req, l.targets, (*leastResponseTimeTarget).lbTarget, l.next)
+
+	if err != nil {
+		return err
+	}
+
+	// An upgraded connection lasts as long as the client wants, which says
+	// nothing about the latency of the target, so it is left out of the
+	// average.
+	if isUpgrade(req) {
+		currentTarget, err = acquireUpgrade(l.targets, currentTarget)
+
+		if err != nil {
+			return err
 		}
 
-		currentTarget.consecutiveRequests.Store(0)
-		currentTarget = sortedTargets[nextIdx]
-		nextIdx++
+		currentTarget.consecutiveRequests.Add(1)
+
+		return serveUpgrade(w, req, currentTarget.Target, l.proxyFactory)
 	}
 
 	timedRW := newTimedResponseWriter(w)
@@ -128,6 +196,11 @@ func (l *leastResponseTime) Handle(w http.ResponseWriter, req *http.Request) err
 	proxy := l.proxyFactory.Create(currentTarget.Host, currentTarget.Port)
 	proxy.ServeHTTP(timedRW, req)
 
+	// A response without a body never reaches Write.
+	if timedRW.endTime.IsZero() {
+		timedRW.endTime = time.Now()
+	}
+
 	responseTime := timedRW.endTime.Sub(timedRW.startTime)
 
 	currentTarget.consecutiveRequests.Add(1)
@@ -137,3 +210,17 @@ func (l *leastResponseTime) Handle(w http.ResponseWriter, req *http.Request) err
 
 	return nil
 }
+
+// HandleConn only accounts for consecutive connections: how long a
+// connection stays open says nothing about the latency of its target.
+func (l *leastResponseTime) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	currentTarget, err := l.next()
+
+	if err != nil {
+		return err
+	}
+
+	currentTarget.consecutiveRequests.Add(1)
+
+	return proxy.ServeConn(conn, currentTarget.Host, currentTarget.Port)
+}
@@ -3,8 +3,7 @@ package algorithms
 import (
 	"net/http/httptest"
 	"testing"
-
-	"sync/atomic"
+	"time"
 
 	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
 	"github.com/stretchr/testify/assert"
@@ -16,21 +15,11 @@ import (
 func buildLRTTargets(targets []*lb.Target) []*leastResponseTimeTarget {
 	lrtTargets := make([]*leastResponseTimeTarget, len(targets))
 
-	lrtTargets[0] = &leastResponseTimeTarget{
-		Target:          targets[0],
-		avgResponseTime: atomic.Int64{},
-		requestCount:    atomic.Int64{},
-	}
+	lrtTargets[0] = newLeastResponseTimeTarget(targets[0], 0)
 	lrtTargets[0].avgResponseTime.Store(112)
-	lrtTargets[0].requestCount.Store(10)
 
-	lrtTargets[1] = &leastResponseTimeTarget{
-		Target:          targets[1],
-		avgResponseTime: atomic.Int64{},
-		requestCount:    atomic.Int64{},
-	}
+	lrtTargets[1] = newLeastResponseTimeTarget(targets[1], 0)
 	lrtTargets[1].avgResponseTime.Store(100)
-	lrtTargets[1].requestCount.Store(3)
 
 	return lrtTargets
 }
@@ -72,7 +61,7 @@ func TestLeastResponseTime_Handle(t *testing.T) {
 
 		lrt := NewLeastResponseTime(targets, proxyFactory, lrtOptions)
 		lrt.targets = lrtTargets
-		lrt.targets[1].Healthy = false
+		lrt.targets[1].SetHealthy(false)
 
 		proxyFactory.On("Create", "localhost", 8080).Return(proxy)
 
@@ -94,8 +83,8 @@ func TestLeastResponseTime_Handle(t *testing.T) {
 
 		lrt := NewLeastResponseTime(targets, proxyFactory, lrtOptions)
 		lrt.targets = lrtTargets
-		lrt.targets[0].Healthy = false
-		lrt.targets[1].Healthy = false
+		lrt.targets[0].SetHealthy(false)
+		lrt.targets[1].SetHealthy(false)
 
 		proxyFactory.On("Create", "localhost", 8081).Return(proxy)
 
@@ -136,7 +125,6 @@ func TestLeastResponseTime_Handle(t *testing.T) {
 		lrtTargets := buildLRTTargets(targets)
 
 		for _, target := range lrtTargets {
-			target.requestCount.Store(0)
 			target.avgResponseTime.Store(0)
 		}
 
@@ -158,7 +146,17 @@ func TestLeastResponseTime_Handle(t *testing.T) {
 		proxy.AssertExpectations(t)
 
 		assert.Equal(t, lrt.requestsCount.Load(), int64(1))
-		assert.Equal(t, lrt.targets[0].requestCount.Load(), int64(1))
-		assert.Equal(t, lrt.targets[1].requestCount.Load(), int64(0))
+		assert.Equal(t, lrt.targets[0].consecutiveRequests.Load(), int64(1))
+		assert.Equal(t, lrt.targets[1].consecutiveRequests.Load(), int64(0))
+	})
+
+	t.Run("Should keep the average response time of a steady target instead of collapsing it", func(t *testing.T) {
+		target := newLeastResponseTimeTarget(getTargets()[0], 0)
+
+		for i := 0; i < 100; i++ {
+			target.setAvgResponseTime(100 * time.Millisecond)
+		}
+
+		assert.InDelta(t, int64(100*time.Millisecond), target.avgResponseTime.Load(), float64(time.Millisecond))
 	})
 }
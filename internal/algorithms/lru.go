@@ -0,0 +1,105 @@
+package algorithms
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// lru is a bounded map that evicts its least recently used entry when full
+// and forgets entries once they expire.
+type lru[V any] struct {
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+	mux      sync.Mutex
+}
+
+func newLRU[V any](capacity int, ttl time.Duration) *lru[V] {
+	return &lru[V]{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get returns the value of key and extends its expiry.
+func (c *lru[V]) Get(key string) (V, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	var zero V
+
+	element, ok := c.entries[key]
+
+	if !ok {
+		return zero, false
+	}
+
+	entry := element.Value.(*lruEntry[V])
+
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+
+		return zero, false
+	}
+
+	entry.expiresAt = c.now().Add(c.ttl)
+	c.order.MoveToFront(element)
+
+	return entry.value, true
+}
+
+func (c *lru[V]) Set(key string, value V) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry[V])
+		entry.value = value
+		entry.expiresAt = c.now().Add(c.ttl)
+		c.order.MoveToFront(element)
+
+		return
+	}
+
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{
+		key:       key,
+		value:     value,
+		expiresAt: c.now().Add(c.ttl),
+	})
+}
+
+func (c *lru[V]) Delete(key string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+func (c *lru[V]) Len() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.order.Len()
+}
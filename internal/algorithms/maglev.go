@@ -0,0 +1,215 @@
+package algorithms
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+const DefaultMaglevTableSize = 65537
+
+// maglevTable is the lookup table built for the targets healthy at a
+// generation of their health.
+type maglevTable struct {
+	generation uint64
+	entries    []*lb.Target
+}
+
+type maglev struct {
+	targets      []*lb.Target
+	tableSize    uint64
+	key          HashKey
+	proxyFactory proxy.ProxyFactory
+	table        atomic.Pointer[maglevTable]
+	// generation is incremented by the targets whenever one of them changes
+	// health.
+	generation *atomic.Uint64
+	mux        sync.Mutex
+}
+
+type NewMaglevOptions struct {
+	Key HashKey
+	// TableSize must be a prime, ideally much larger than the number of
+	// targets.
+	TableSize int
+}
+
+func NewMaglev(targets []*lb.Target, proxyFactory proxy.ProxyFactory, opts NewMaglevOptions) *maglev {
+	tableSize := opts.TableSize
+
+	if tableSize <= 0 {
+		tableSize = DefaultMaglevTableSize
+	}
+
+	generation := &atomic.Uint64{}
+
+	for _, target := range targets {
+		target.WatchHealth(generation)
+	}
+
+	return &maglev{
+		targets:      targets,
+		tableSize:    uint64(tableSize),
+		key:          opts.Key,
+		proxyFactory: proxyFactory,
+		generation:   generation,
+	}
+}
+
+// IsPrime reports whether n can be used as a maglev table size.
+func IsPrime(n int) bool {
+	if n < 2 {
+		return false
+	}
+
+	for i := 2; i*i <= n; i++ {
+		if n%i == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// populate fills the table as described in the Maglev paper: every healthy
+// target walks its own permutation of the slots and takes turns claiming
+// the next free one, which gives each target a share of the slots matching
+// its weight.
+func (m *maglev) populate(healthy []bool) *maglevTable {
+	table := &maglevTable{
+		entries: make([]*lb.Target, m.tableSize),
+	}
+
+	var offsets, skips, nexts []uint64
+	var candidates []*lb.Target
+
+	for i, target := range m.targets {
+		if !healthy[i] {
+			continue
+		}
+
+		name := fmt.Sprintf("%s:%d", target.Host, target.Port)
+
+		candidates = append(candidates, target)
+		offsets = append(offsets, hashString(name)%m.tableSize)
+		skips = append(skips, hashString(name+"#skip")%(m.tableSize-1)+1)
+		nexts = append(nexts, 0)
+	}
+
+	if len(candidates) == 0 {
+		return table
+	}
+
+	filled := uint64(0)
+
+	// Every turn a target claims as many slots as its weight, so its share
+	// of the table follows its weight.
+	for {
+		for i, target := range candidates {
+			for claimed := 0; claimed < target.EffectiveWeight(); claimed++ {
+				slot := (offsets[i] + nexts[i]*skips[i]) % m.tableSize
+
+				for table.entries[slot] != nil {
+					nexts[i]++
+					slot = (offsets[i] + nexts[i]*skips[i]) % m.tableSize
+				}
+
+				table.entries[slot] = target
+				nexts[i]++
+				filled++
+
+				if filled == m.tableSize {
+					return table
+				}
+			}
+		}
+	}
+}
+
+// currentTable returns the table for the targets healthy right now,
+// rebuilding it when a target changed health since the last build.
+func (m *maglev) currentTable() *maglevTable {
+	table := m.table.Load()
+	generation := m.generation.Load()
+
+	if table != nil && table.generation == generation {
+		return table
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	table = m.table.Load()
+
+	if table != nil && table.generation == generation {
+		return table
+	}
+
+	// The generation is read before the health, so a change racing with
+	// the build leaves the table stale rather than missed.
+	healthy := make([]bool, len(m.targets))
+
+	for i, target := range m.targets {
+		healthy[i] = target.IsHealthy()
+	}
+
+	table = m.populate(healthy)
+	table.generation = generation
+	m.table.Store(table)
+
+	return table
+}
+
+func (m *maglev) next(key string) (*lb.Target, error) {
+	table := m.currentTable()
+	target := table.entries[hashString(key)%m.tableSize]
+
+	if target == nil {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
+	return target, nil
+}
+
+func (m *maglev) Handle(w http.ResponseWriter, req *http.Request) error {
+	currentTarget, err := pickPinned(req, m.targets, itself, func() (*lb.Target, error) {
+		return m.next(m.key.value(req))
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if isUpgrade(req) {
+		currentTarget, err = acquireUpgrade(m.targets, currentTarget)
+
+		if err != nil {
+			return err
+		}
+
+		return serveUpgrade(w, req, currentTarget, m.proxyFactory)
+	}
+
+	proxy := m.proxyFactory.Create(currentTarget.Host, currentTarget.Port)
+	proxy.ServeHTTP(w, req)
+
+	return nil
+}
+
+func (m *maglev) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	currentTarget, err := m.next(connKey(conn))
+
+	if err != nil {
+		return err
+	}
+
+	return proxy.ServeConn(conn, currentTarget.Host, currentTarget.Port)
+}
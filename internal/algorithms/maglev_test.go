@@ -0,0 +1,121 @@
+package algorithms
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+func TestMaglev_Handle(t *testing.T) {
+	t.Run("Should send the same key to the same target", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		m := NewMaglev(buildHashTargets(5), proxyFactory, NewMaglevOptions{
+			Key:       HashKey{Source: HashKeyQuery, Name: "tenant"},
+			TableSize: 251,
+		})
+
+		expected, err := m.next("acme")
+		assert.Nil(t, err)
+
+		proxyFactory.On("Create", expected.Host, expected.Port).Return(proxy)
+
+		for i := 0; i < 3; i++ {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "http://localhost/?tenant=acme", nil)
+			r.RemoteAddr = fmt.Sprintf("192.168.0.%d:5000", i)
+
+			proxy.On("ServeHTTP", w, r).Return()
+
+			assert.Nil(t, m.Handle(w, r))
+		}
+
+		proxyFactory.AssertNumberOfCalls(t, "Create", 3)
+	})
+
+	t.Run("Should give every target an even share of the table", func(t *testing.T) {
+		targets := buildHashTargets(7)
+		m := NewMaglev(targets, &MockedProxyFactory{}, NewMaglevOptions{})
+
+		counts := map[int]int{}
+
+		for _, target := range m.currentTable().entries {
+			counts[target.Port]++
+		}
+
+		assert.Len(t, counts, 7)
+
+		for _, count := range counts {
+			assert.InDelta(t, DefaultMaglevTableSize/7, count, 1)
+		}
+	})
+
+	t.Run("Should move few keys of healthy targets when a target becomes unhealthy", func(t *testing.T) {
+		targets := buildHashTargets(10)
+		m := NewMaglev(targets, &MockedProxyFactory{}, NewMaglevOptions{})
+
+		before := m.currentTable().entries
+		targets[3].SetHealthy(false)
+		after := m.currentTable().entries
+
+		moved, kept := 0, 0
+
+		for slot, target := range before {
+			assert.NotEqual(t, targets[3], after[slot])
+
+			if target == targets[3] {
+				continue
+			}
+
+			if after[slot] == target {
+				kept++
+			} else {
+				moved++
+			}
+		}
+
+		assert.Less(t, float64(moved)/float64(moved+kept), 0.05)
+	})
+
+	t.Run("Should rebuild the table only when the healthy set changes", func(t *testing.T) {
+		targets := buildHashTargets(3)
+		m := NewMaglev(targets, &MockedProxyFactory{}, NewMaglevOptions{TableSize: 101})
+
+		table := m.currentTable()
+
+		assert.Same(t, table, m.currentTable())
+
+		targets[0].SetHealthy(false)
+
+		assert.NotSame(t, table, m.currentTable())
+	})
+
+	t.Run("Should keep the table when a target of another group changes health", func(t *testing.T) {
+		m := NewMaglev(buildHashTargets(3), &MockedProxyFactory{}, NewMaglevOptions{TableSize: 101})
+		other := buildHashTargets(1)
+		NewMaglev(other, &MockedProxyFactory{}, NewMaglevOptions{TableSize: 101})
+
+		table := m.currentTable()
+
+		other[0].SetHealthy(false)
+
+		assert.Same(t, table, m.currentTable())
+	})
+
+	t.Run("Should return an error when no target is healthy", func(t *testing.T) {
+		targets := buildHashTargets(2)
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(false)
+
+		m := NewMaglev(targets, &MockedProxyFactory{}, NewMaglevOptions{TableSize: 101})
+
+		_, err := m.next("client")
+
+		assert.Equal(t, errs.ErrNoHealthyTargets, err)
+	})
+}
@@ -0,0 +1,198 @@
+package algorithms
+
+import (
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+// The loads power of two choices can compare targets on.
+const (
+	P2CCompareInFlight = "in-flight"
+	P2CCompareEwma     = "ewma"
+)
+
+type p2cTarget struct {
+	*lb.Target
+	inFlight atomic.Int64
+	latency  *ewma
+}
+
+func (t *p2cTarget) lbTarget() *lb.Target {
+	return t.Target
+}
+
+// load is the cost of sending one more request to the target, relative to
+// its weight.
+func (t *p2cTarget) load(compare string, now time.Time) float64 {
+	if compare == P2CCompareEwma {
+		return t.latency.get(now) / t.SlowStartWeight(now)
+	}
+
+	return float64(t.inFlight.Load()+1) / t.SlowStartWeight(now)
+}
+
+// p2c samples two healthy targets at random and sends the request to the
+// less loaded one. Unlike least-* algorithms it only looks at the targets it
+// draws, scanning only when most of them are unhealthy, and unlike round
+// robin it shares no cursor between requests.
+type p2c struct {
+	targets      []*p2cTarget
+	proxyFactory proxy.ProxyFactory
+	compare      string
+	randIntn     func(int) int
+}
+
+type NewP2COptions struct {
+	// Compare is P2CCompareInFlight (the default) or P2CCompareEwma.
+	Compare string
+	// Decay is how fast the latency average forgets, for P2CCompareEwma.
+	Decay time.Duration
+}
+
+func NewP2C(targets []*lb.Target, proxyFactory proxy.ProxyFactory, opts NewP2COptions) *p2c {
+	p2cTargets := make([]*p2cTarget, len(targets))
+
+	for i, target := range targets {
+		p2cTargets[i] = &p2cTarget{
+			Target:  target,
+			latency: newEwma(opts.Decay),
+		}
+	}
+
+	compare := opts.Compare
+
+	if compare == "" {
+		compare = P2CCompareInFlight
+	}
+
+	return &p2c{
+		targets:      p2cTargets,
+		proxyFactory: proxyFactory,
+		compare:      compare,
+		randIntn:     rand.IntN,
+	}
+}
+
+// p2cDraws bounds how many times a pick landing on an unhealthy target is
+// redrawn before falling back to a scan.
+const p2cDraws = 3
+
+// pick draws a healthy target other than exclude, or returns -1 when there
+// is none. Drawing from one target fewer and skipping exclude keeps both
+// choices distinct.
+func (p *p2c) pick(exclude int) int {
+	n := len(p.targets)
+	candidates := n
+
+	if exclude >= 0 {
+		candidates--
+	}
+
+	if candidates <= 0 {
+		return -1
+	}
+
+	var i int
+
+	for draw := 0; draw < p2cDraws; draw++ {
+		i = p.randIntn(candidates)
+
+		if exclude >= 0 && i >= exclude {
+			i++
+		}
+
+		if p.targets[i].IsHealthy() {
+			return i
+		}
+	}
+
+	// Most targets are unhealthy: scan on from the last draw instead of
+	// drawing again.
+	for step := 1; step < n; step++ {
+		j := (i + step) % n
+
+		if j != exclude && p.targets[j].IsHealthy() {
+			return j
+		}
+	}
+
+	return -1
+}
+
+func (p *p2c) next() (*p2cTarget, error) {
+	first := p.pick(-1)
+
+	if first < 0 {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
+	second := p.pick(first)
+
+	if second < 0 {
+		return p.targets[first], nil
+	}
+
+	now := time.Now()
+
+	if p.targets[second].load(p.compare, now) < p.targets[first].load(p.compare, now) {
+		return p.targets[second], nil
+	}
+
+	return p.targets[first], nil
+}
+
+func (p *p2c) Handle(w http.ResponseWriter, req *http.Request) error {
+	currentTarget, err := pickPinned(req, p.targets, (*p2cTarget).lbTarget, p.next)
+
+	if err != nil {
+		return err
+	}
+
+	upgrade := isUpgrade(req)
+
+	if upgrade {
+		currentTarget, err = acquireUpgrade(p.targets, currentTarget)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	currentTarget.inFlight.Add(1)
+	defer currentTarget.inFlight.Add(-1)
+
+	if upgrade {
+		return serveUpgrade(w, req, currentTarget.Target, p.proxyFactory)
+	}
+
+	startTime := time.Now()
+
+	proxy := p.proxyFactory.Create(currentTarget.Host, currentTarget.Port)
+	proxy.ServeHTTP(w, req)
+
+	currentTarget.latency.observe(float64(time.Since(startTime)), time.Now())
+
+	return nil
+}
+
+func (p *p2c) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	currentTarget, err := p.next()
+
+	if err != nil {
+		return err
+	}
+
+	currentTarget.inFlight.Add(1)
+	defer currentTarget.inFlight.Add(-1)
+
+	return proxy.ServeConn(conn, currentTarget.Host, currentTarget.Port)
+}
@@ -0,0 +1,123 @@
+package algorithms
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+func getP2CTargets() []*lb.Target {
+	return healthyTargets([]*lb.Target{
+		{Host: "localhost", Port: 8080},
+		{Host: "localhost", Port: 8081},
+		{Host: "localhost", Port: 8082},
+		{Host: "localhost", Port: 8083},
+	})
+}
+
+func TestP2C_Handle(t *testing.T) {
+	t.Run("Should call the less loaded of the two sampled targets", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		p := NewP2C(getP2CTargets(), proxyFactory, NewP2COptions{})
+		p.targets[0].inFlight.Store(3)
+		p.targets[2].inFlight.Store(1)
+
+		draws := []int{0, 1}
+		p.randIntn = func(n int) int {
+			draw := draws[0]
+			draws = draws[1:]
+			return draw
+		}
+
+		proxyFactory.On("Create", "localhost", 8082).Return(proxy)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost", nil)
+
+		var inFlight int64
+		proxy.On("ServeHTTP", w, r).Run(func(_ mock.Arguments) {
+			inFlight = p.targets[2].inFlight.Load()
+		}).Return()
+
+		assert.Nil(t, p.Handle(w, r))
+
+		assert.Equal(t, int64(2), inFlight)
+		assert.Equal(t, int64(1), p.targets[2].inFlight.Load())
+		proxyFactory.AssertExpectations(t)
+	})
+
+	t.Run("Should never pick the most loaded target", func(t *testing.T) {
+		p := NewP2C(getP2CTargets(), &MockedProxyFactory{}, NewP2COptions{})
+		p.randIntn = seededIntn()
+
+		for i, inFlight := range []int64{9, 3, 5, 0} {
+			p.targets[i].inFlight.Store(inFlight)
+		}
+
+		counts := map[int]int{}
+
+		for i := 0; i < 1000; i++ {
+			target, err := p.next()
+
+			assert.Nil(t, err)
+			counts[target.Port]++
+		}
+
+		assert.Zero(t, counts[8080])
+		assert.Greater(t, counts[8083], counts[8081])
+		assert.Greater(t, counts[8081], counts[8082])
+	})
+
+	t.Run("Should compare on the latency average when configured", func(t *testing.T) {
+		p := NewP2C(getP2CTargets(), &MockedProxyFactory{}, NewP2COptions{Compare: P2CCompareEwma})
+		p.randIntn = seededIntn()
+
+		for i, latency := range []time.Duration{time.Second, 10 * time.Millisecond, 50 * time.Millisecond, 20 * time.Millisecond} {
+			p.targets[i].latency.observe(float64(latency), time.Now())
+		}
+
+		for i := 0; i < 1000; i++ {
+			target, err := p.next()
+
+			assert.Nil(t, err)
+			assert.NotEqual(t, 8080, target.Port)
+		}
+	})
+
+	t.Run("Should only pick healthy targets", func(t *testing.T) {
+		targets := getP2CTargets()
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(false)
+		targets[3].SetHealthy(false)
+
+		p := NewP2C(targets, &MockedProxyFactory{}, NewP2COptions{})
+		p.randIntn = seededIntn()
+
+		for i := 0; i < 100; i++ {
+			target, err := p.next()
+
+			assert.Nil(t, err)
+			assert.Equal(t, 8082, target.Port)
+		}
+	})
+
+	t.Run("Should return an error when no target is healthy", func(t *testing.T) {
+		targets := getP2CTargets()
+
+		for _, target := range targets {
+			target.SetHealthy(false)
+		}
+
+		_, err := NewP2C(targets, &MockedProxyFactory{}, NewP2COptions{}).next()
+
+		assert.Equal(t, errs.ErrNoHealthyTargets, err)
+	})
+}
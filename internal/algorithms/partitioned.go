@@ -0,0 +1,131 @@
+package algorithms
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+type partition struct {
+	targets   []*lb.Target
+	algorithm alg.Algorithm
+}
+
+// partitioned splits the targets of a target group, into failover tiers or
+// zones, and sends each request to a partition drawn by the share of the
+// traffic loads gives it, to be balanced by the algorithm of the partition.
+type partitioned struct {
+	partitions []*partition
+	// loads returns the share of the traffic of each partition, in any unit.
+	loads    func() []int
+	randIntn func(int) int
+}
+
+// newPartitioned groups the targets by key, in ascending order of key, and
+// builds one algorithm per group with newAlgorithm, so each partition
+// balances its own targets like the target group would.
+func newPartitioned[K cmp.Ordered](targets []*lb.Target, key func(*lb.Target) K, newAlgorithm func([]*lb.Target) (alg.Algorithm, error)) (*partitioned, error) {
+	byKey := make(map[K][]*lb.Target)
+
+	for _, target := range targets {
+		byKey[key(target)] = append(byKey[key(target)], target)
+	}
+
+	keys := make([]K, 0, len(byKey))
+
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	p := &partitioned{
+		partitions: make([]*partition, len(keys)),
+		randIntn:   rand.IntN,
+	}
+
+	for i, k := range keys {
+		algorithm, err := newAlgorithm(byKey[k])
+
+		if err != nil {
+			return nil, err
+		}
+
+		p.partitions[i] = &partition{
+			targets:   byKey[k],
+			algorithm: algorithm,
+		}
+	}
+
+	return p, nil
+}
+
+func (p *partitioned) next() (*partition, error) {
+	loads := p.loads()
+	total := 0
+
+	for _, load := range loads {
+		total += load
+	}
+
+	if total == 0 {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
+	n := p.randIntn(total)
+
+	for i, load := range loads {
+		if n < load {
+			return p.partitions[i], nil
+		}
+
+		n -= load
+	}
+
+	return nil, errs.ErrNoHealthyTargets
+}
+
+// Handle sends a pinned request to the partition of its target, whose
+// algorithm honours the pin, and draws a partition for the others.
+func (p *partitioned) Handle(w http.ResponseWriter, req *http.Request) error {
+	for _, partition := range p.partitions {
+		if _, ok := pinnedTarget(req, partition.targets, itself); ok {
+			return partition.algorithm.Handle(w, req)
+		}
+	}
+
+	partition, err := p.next()
+
+	if err != nil {
+		return err
+	}
+
+	return partition.algorithm.Handle(w, req)
+}
+
+func (p *partitioned) Unwrap() []alg.Algorithm {
+	algorithms := make([]alg.Algorithm, len(p.partitions))
+
+	for i, partition := range p.partitions {
+		algorithms[i] = partition.algorithm
+	}
+
+	return algorithms
+}
+
+func (p *partitioned) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	partition, err := p.next()
+
+	if err != nil {
+		return err
+	}
+
+	return delegateConn(partition.algorithm, conn, proxy)
+}
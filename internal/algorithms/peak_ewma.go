@@ -0,0 +1,145 @@
+package algorithms
+
+import (
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+type peakEwmaTarget struct {
+	*lb.Target
+	inFlight atomic.Int64
+	latency  *ewma
+}
+
+func (t *peakEwmaTarget) lbTarget() *lb.Target {
+	return t.Target
+}
+
+// cost estimates how long one more request would take on the target: its
+// peak latency average times the requests it would be queued with, relative
+// to its weight. The extra nanosecond keeps in-flight requests relevant for
+// targets with no latency yet.
+func (t *peakEwmaTarget) cost(now time.Time) float64 {
+	return (t.latency.get(now) + 1) * float64(t.inFlight.Load()+1) / t.SlowStartWeight(now)
+}
+
+type peakEwma struct {
+	targets      []*peakEwmaTarget
+	proxyFactory proxy.ProxyFactory
+	// offset rotates where the scan starts, so that targets tied on cost
+	// take turns.
+	offset atomic.Int64
+}
+
+type NewPeakEwmaOptions struct {
+	// Decay is how fast latency peaks are forgotten.
+	Decay time.Duration
+}
+
+func NewPeakEwma(targets []*lb.Target, proxyFactory proxy.ProxyFactory, opts NewPeakEwmaOptions) *peakEwma {
+	peTargets := make([]*peakEwmaTarget, len(targets))
+
+	for i, target := range targets {
+		peTargets[i] = &peakEwmaTarget{
+			Target:  target,
+			latency: newPeakEwma(opts.Decay),
+		}
+	}
+
+	return &peakEwma{
+		targets:      peTargets,
+		proxyFactory: proxyFactory,
+	}
+}
+
+func (p *peakEwma) next() (*peakEwmaTarget, error) {
+	numTargets := int64(len(p.targets))
+
+	if numTargets == 0 {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
+	start := (p.offset.Add(1) - 1) % numTargets
+	now := time.Now()
+
+	var selected *peakEwmaTarget
+	var selectedCost float64
+
+	for i := int64(0); i < numTargets; i++ {
+		target := p.targets[(start+i)%numTargets]
+
+		if !target.IsHealthy() {
+			continue
+		}
+
+		cost := target.cost(now)
+
+		if selected == nil || cost < selectedCost {
+			selected = target
+			selectedCost = cost
+		}
+	}
+
+	if selected == nil {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
+	return selected, nil
+}
+
+func (p *peakEwma) Handle(w http.ResponseWriter, req *http.Request) error {
+	currentTarget, err := pickPinned(req, p.targets, (*peakEwmaTarget).lbTarget, p.next)
+
+	if err != nil {
+		return err
+	}
+
+	upgrade := isUpgrade(req)
+
+	if upgrade {
+		currentTarget, err = acquireUpgrade(p.targets, currentTarget)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	currentTarget.inFlight.Add(1)
+	defer currentTarget.inFlight.Add(-1)
+
+	if upgrade {
+		return serveUpgrade(w, req, currentTarget.Target, p.proxyFactory)
+	}
+
+	startTime := time.Now()
+
+	proxy := p.proxyFactory.Create(currentTarget.Host, currentTarget.Port)
+	proxy.ServeHTTP(w, req)
+
+	currentTarget.latency.observe(float64(time.Since(startTime)), time.Now())
+
+	return nil
+}
+
+// HandleConn only accounts for in-flight connections, their duration says
+// nothing about the latency of the target.
+func (p *peakEwma) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	currentTarget, err := p.next()
+
+	if err != nil {
+		return err
+	}
+
+	currentTarget.inFlight.Add(1)
+	defer currentTarget.inFlight.Add(-1)
+
+	return proxy.ServeConn(conn, currentTarget.Host, currentTarget.Port)
+}
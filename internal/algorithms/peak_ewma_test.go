@@ -0,0 +1,133 @@
+package algorithms
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+func TestPeakEwma_Handle(t *testing.T) {
+	t.Run("Should call the target with the lowest latency and record the request", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		pe := NewPeakEwma(getTargets(), proxyFactory, NewPeakEwmaOptions{})
+		pe.targets[0].latency.observe(float64(100*time.Millisecond), time.Now())
+		pe.targets[1].latency.observe(float64(10*time.Millisecond), time.Now())
+
+		proxyFactory.On("Create", "localhost", 8081).Return(proxy)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost", nil)
+
+		var inFlight int64
+		proxy.On("ServeHTTP", w, r).Run(func(_ mock.Arguments) {
+			inFlight = pe.targets[1].inFlight.Load()
+		}).Return()
+
+		assert.Nil(t, pe.Handle(w, r))
+
+		assert.Equal(t, int64(1), inFlight)
+		assert.Equal(t, int64(0), pe.targets[1].inFlight.Load())
+		assert.Less(t, pe.targets[1].latency.get(time.Now()), float64(10*time.Millisecond))
+		proxyFactory.AssertExpectations(t)
+	})
+
+	t.Run("Should weigh latency by the in-flight requests", func(t *testing.T) {
+		pe := NewPeakEwma(getTargets(), &MockedProxyFactory{}, NewPeakEwmaOptions{})
+		pe.targets[0].latency.observe(float64(20*time.Millisecond), time.Now())
+		pe.targets[1].latency.observe(float64(10*time.Millisecond), time.Now())
+		pe.targets[1].inFlight.Store(3)
+
+		target, err := pe.next()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 8080, target.Port)
+	})
+
+	t.Run("Should take turns between targets without latency", func(t *testing.T) {
+		pe := NewPeakEwma(getTargets(), &MockedProxyFactory{}, NewPeakEwmaOptions{})
+
+		counts := map[int]int{}
+
+		for i := 0; i < 10; i++ {
+			target, err := pe.next()
+
+			assert.Nil(t, err)
+			counts[target.Port]++
+		}
+
+		assert.Equal(t, map[int]int{8080: 5, 8081: 5}, counts)
+	})
+
+	t.Run("Should return an error when no target is healthy", func(t *testing.T) {
+		targets := getTargets()
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(false)
+
+		_, err := NewPeakEwma(targets, &MockedProxyFactory{}, NewPeakEwmaOptions{}).next()
+
+		assert.Equal(t, errs.ErrNoHealthyTargets, err)
+	})
+}
+
+func TestEwma(t *testing.T) {
+	t.Run("Should average samples with a weight that decays over time", func(t *testing.T) {
+		now := time.Now()
+		e := newEwma(time.Second)
+
+		e.observe(100, now)
+		e.observe(0, now.Add(time.Second))
+
+		assert.InDelta(t, 36.8, e.get(now.Add(time.Second)), 0.1)
+	})
+
+	t.Run("Should not collapse toward zero as requests add up", func(t *testing.T) {
+		now := time.Now()
+		e := newEwma(time.Second)
+
+		for i := 0; i < 1000; i++ {
+			e.observe(50, now.Add(time.Duration(i)*time.Millisecond))
+		}
+
+		assert.InDelta(t, 50, e.get(now), 0.001)
+	})
+
+	t.Run("Should jump to a peak and decay from it", func(t *testing.T) {
+		now := time.Now()
+		e := newPeakEwma(time.Second)
+
+		e.observe(10, now)
+		e.observe(500, now.Add(10*time.Millisecond))
+
+		assert.Equal(t, float64(500), e.get(now.Add(10*time.Millisecond)))
+
+		e.observe(10, now.Add(2*time.Second))
+
+		assert.Less(t, e.get(now.Add(2*time.Second)), float64(100))
+	})
+
+	t.Run("Should compare a sample with the peak decayed to its time", func(t *testing.T) {
+		now := time.Now()
+		e := newPeakEwma(time.Second)
+
+		e.observe(float64(time.Second), now)
+		e.observe(float64(500*time.Millisecond), now.Add(time.Second))
+
+		assert.Equal(t, float64(500*time.Millisecond), e.get(now.Add(time.Second)))
+	})
+
+	t.Run("Should forget a peak while no request comes in", func(t *testing.T) {
+		now := time.Now()
+		e := newPeakEwma(time.Second)
+
+		e.observe(500, now)
+
+		assert.Less(t, e.get(now.Add(5*time.Second)), float64(5))
+	})
+}
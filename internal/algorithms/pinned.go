@@ -0,0 +1,53 @@
+package algorithms
+
+import (
+	"context"
+	"net/http"
+
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+type pinnedTargetKey struct{}
+
+// withPinnedTarget asks the algorithm handling req to send it to target,
+// which a sticky wrapper pinned the client to. The algorithm still accounts
+// for the request as if it had picked the target itself.
+func withPinnedTarget(req *http.Request, target *lb.Target) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), pinnedTargetKey{}, target))
+}
+
+// pinnedTarget returns the one of targets req is pinned to, as long as it is
+// healthy. target gives the lb.Target behind each of targets.
+func pinnedTarget[T upgradeTarget](req *http.Request, targets []T, target func(T) *lb.Target) (T, bool) {
+	var none T
+
+	pinned, ok := req.Context().Value(pinnedTargetKey{}).(*lb.Target)
+
+	if !ok || !pinned.IsHealthy() {
+		return none, false
+	}
+
+	for _, t := range targets {
+		if target(t) == pinned {
+			return t, true
+		}
+	}
+
+	return none, false
+}
+
+// pickPinned returns the target req is pinned to or, for requests that are not
+// pinned, the one next picks.
+func pickPinned[T upgradeTarget](req *http.Request, targets []T, target func(T) *lb.Target, next func() (T, error)) (T, error) {
+	if pinned, ok := pinnedTarget(req, targets, target); ok {
+		return pinned, nil
+	}
+
+	return next()
+}
+
+// itself is the target func of algorithms that balance the lb.Targets as
+// they are.
+func itself(target *lb.Target) *lb.Target {
+	return target
+}
@@ -0,0 +1,71 @@
+package algorithms
+
+import (
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+const DefaultFailoverHealthyPercent = 70
+
+type NewPriorityTiersOptions struct {
+	HealthyPercent int
+}
+
+// NewPriorityTiers splits the targets into tiers by Target.Priority, 0 being
+// the primary one. A tier with at least healthyPercent of its targets
+// healthy takes all of the traffic left to it; below that, it takes a share
+// proportional to its health and the rest spills over to the next tier.
+func NewPriorityTiers(targets []*lb.Target, newAlgorithm func([]*lb.Target) (alg.Algorithm, error), opts NewPriorityTiersOptions) (*partitioned, error) {
+	healthyPercent := opts.HealthyPercent
+
+	if healthyPercent <= 0 {
+		healthyPercent = DefaultFailoverHealthyPercent
+	}
+
+	p, err := newPartitioned(targets, func(target *lb.Target) int {
+		return target.Priority
+	}, newAlgorithm)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.loads = func() []int {
+		return tierLoads(p.partitions, healthyPercent)
+	}
+
+	return p, nil
+}
+
+// tierLoads returns the percentage of the traffic each tier receives.
+func tierLoads(tiers []*partition, healthyPercent int) []int {
+	loads := make([]int, len(tiers))
+	remaining := 100
+	total := 0
+
+	for i, tier := range tiers {
+		healthy := 0
+
+		for _, target := range tier.targets {
+			if target.IsHealthy() {
+				healthy++
+			}
+		}
+
+		health := min(100, healthy*100*100/(len(tier.targets)*healthyPercent))
+
+		loads[i] = remaining * health / 100
+		remaining -= loads[i]
+		total += loads[i]
+	}
+
+	// When no tier is healthy enough, what is left goes to the tiers in
+	// proportion to the share they already have.
+	if remaining > 0 && total > 0 {
+		for i := range loads {
+			loads[i] += remaining * loads[i] / total
+		}
+	}
+
+	return loads
+}
@@ -0,0 +1,155 @@
+package algorithms
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+func getTieredTargets() []*lb.Target {
+	targets := make([]*lb.Target, 0, 6)
+
+	for i := 0; i < 6; i++ {
+		targets = append(targets, &lb.Target{
+			Host:     "localhost",
+			Port:     8080 + i,
+			Priority: i / 2,
+		})
+	}
+
+	return healthyTargets(targets)
+}
+
+func newTieredRoundRobin(targets []*lb.Target, proxyFactory *MockedProxyFactory, opts NewPriorityTiersOptions) *partitioned {
+	p, _ := NewPriorityTiers(targets, func(tierTargets []*lb.Target) (alg.Algorithm, error) {
+		return NewRoundRobin(tierTargets, proxyFactory), nil
+	}, opts)
+
+	return p
+}
+
+func TestPriorityTiers_Handle(t *testing.T) {
+	t.Run("Should send all the traffic to the primary tier while it is healthy enough", func(t *testing.T) {
+		targets := getTieredTargets()
+		targets[0].SetHealthy(false)
+
+		p := newTieredRoundRobin(targets, &MockedProxyFactory{}, NewPriorityTiersOptions{HealthyPercent: 50})
+
+		assert.Equal(t, []int{100, 0, 0}, p.loads())
+	})
+
+	t.Run("Should spill over to the next tiers as the health drops", func(t *testing.T) {
+		targets := getTieredTargets()
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(false)
+		targets[2].SetHealthy(false)
+
+		p := newTieredRoundRobin(targets, &MockedProxyFactory{}, NewPriorityTiersOptions{HealthyPercent: 50})
+
+		assert.Equal(t, []int{0, 100, 0}, p.loads())
+
+		targets[1].SetHealthy(true)
+		targets[3].SetHealthy(false)
+		targets[4].SetHealthy(false)
+		targets[5].SetHealthy(false)
+
+		// The only tier with healthy targets gets everything.
+		assert.Equal(t, []int{100, 0, 0}, p.loads())
+	})
+
+	t.Run("Should split the traffic between tiers in proportion to their health", func(t *testing.T) {
+		targets := getTieredTargets()
+
+		p := newTieredRoundRobin(targets, &MockedProxyFactory{}, NewPriorityTiersOptions{HealthyPercent: 100})
+
+		targets[0].SetHealthy(false)
+		targets[2].SetHealthy(false)
+
+		assert.Equal(t, []int{50, 25, 25}, p.loads())
+	})
+
+	t.Run("Should default to a healthy percent of 70", func(t *testing.T) {
+		targets := getTieredTargets()
+		targets[0].SetHealthy(false)
+
+		p := newTieredRoundRobin(targets, &MockedProxyFactory{}, NewPriorityTiersOptions{})
+
+		// Half of the primary tier is healthy, below 70%.
+		assert.Equal(t, []int{71, 29, 0}, p.loads())
+		assert.Len(t, p.partitions, 3)
+	})
+
+	t.Run("Should handle the request with the algorithm of the picked tier", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		targets := getTieredTargets()
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(false)
+
+		p := newTieredRoundRobin(targets, proxyFactory, NewPriorityTiersOptions{HealthyPercent: 50})
+		p.randIntn = seededIntn()
+
+		proxyFactory.On("Create", "localhost", 8082).Return(proxy)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "http://localhost", nil)
+
+		proxy.On("ServeHTTP", w, req).Return()
+
+		assert.Nil(t, p.Handle(w, req))
+
+		proxyFactory.AssertExpectations(t)
+		proxy.AssertExpectations(t)
+	})
+
+	t.Run("Should send a pinned request to the tier of its target", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		targets := getTieredTargets()
+
+		p := newTieredRoundRobin(targets, proxyFactory, NewPriorityTiersOptions{HealthyPercent: 50})
+
+		proxyFactory.On("Create", "localhost", 8084).Return(proxy)
+
+		w := httptest.NewRecorder()
+		req := withPinnedTarget(httptest.NewRequest("GET", "http://localhost", nil), targets[4])
+
+		proxy.On("ServeHTTP", w, req).Return()
+
+		assert.Nil(t, p.Handle(w, req))
+
+		proxyFactory.AssertExpectations(t)
+		proxy.AssertExpectations(t)
+	})
+
+	t.Run("Should return an error when no tier has healthy targets", func(t *testing.T) {
+		targets := getTieredTargets()
+
+		for _, target := range targets {
+			target.SetHealthy(false)
+		}
+
+		p := newTieredRoundRobin(targets, &MockedProxyFactory{}, NewPriorityTiersOptions{HealthyPercent: 50})
+
+		err := p.Handle(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost", nil))
+
+		assert.ErrorIs(t, err, errs.ErrNoHealthyTargets)
+	})
+
+	t.Run("Should return the error of the algorithm of a tier", func(t *testing.T) {
+		_, err := NewPriorityTiers(getTieredTargets(), func(tierTargets []*lb.Target) (alg.Algorithm, error) {
+			return nil, errors.New("invalid options")
+		}, NewPriorityTiersOptions{})
+
+		assert.EqualError(t, err, "invalid options")
+	})
+}
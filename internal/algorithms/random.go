@@ -0,0 +1,93 @@
+package algorithms
+
+import (
+	"math/rand/v2"
+	"net"
+	"net/http"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+// random picks a healthy target with a probability matching its weight. It
+// keeps no shared state besides the source of randomness.
+type random struct {
+	targets      []*lb.Target
+	proxyFactory proxy.ProxyFactory
+	randIntn     func(int) int
+}
+
+func NewRandom(targets []*lb.Target, proxyFactory proxy.ProxyFactory) *random {
+	return &random{
+		targets:      targets,
+		proxyFactory: proxyFactory,
+		randIntn:     rand.IntN,
+	}
+}
+
+func (r *random) next() (*lb.Target, error) {
+	totalWeight := 0
+
+	for _, target := range r.targets {
+		if target.IsHealthy() {
+			totalWeight += target.EffectiveWeight()
+		}
+	}
+
+	if totalWeight == 0 {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
+	n := r.randIntn(totalWeight)
+
+	for _, target := range r.targets {
+		if !target.IsHealthy() {
+			continue
+		}
+
+		if n < target.EffectiveWeight() {
+			return target, nil
+		}
+
+		n -= target.EffectiveWeight()
+	}
+
+	// A target turned unhealthy between both loops.
+	return nil, errs.ErrNoHealthyTargets
+}
+
+func (r *random) Handle(w http.ResponseWriter, req *http.Request) error {
+	currentTarget, err := pickPinned(req, r.targets, itself, r.next)
+
+	if err != nil {
+		return err
+	}
+
+	if isUpgrade(req) {
+		currentTarget, err = acquireUpgrade(r.targets, currentTarget)
+
+		if err != nil {
+			return err
+		}
+
+		return serveUpgrade(w, req, currentTarget, r.proxyFactory)
+	}
+
+	proxy := r.proxyFactory.Create(currentTarget.Host, currentTarget.Port)
+	proxy.ServeHTTP(w, req)
+
+	return nil
+}
+
+func (r *random) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	currentTarget, err := r.next()
+
+	if err != nil {
+		return err
+	}
+
+	return proxy.ServeConn(conn, currentTarget.Host, currentTarget.Port)
+}
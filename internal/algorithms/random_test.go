@@ -0,0 +1,80 @@
+package algorithms
+
+import (
+	"math/rand/v2"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+func seededIntn() func(int) int {
+	return rand.New(rand.NewPCG(1, 2)).IntN
+}
+
+func TestRandom_Handle(t *testing.T) {
+	t.Run("Should call the randomly picked target", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		r := NewRandom(getTargets(), proxyFactory)
+		r.randIntn = func(n int) int { return 1 }
+
+		proxyFactory.On("Create", "localhost", 8081).Return(proxy)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "http://localhost", nil)
+
+		proxy.On("ServeHTTP", w, req).Return()
+
+		assert.Nil(t, r.Handle(w, req))
+
+		proxyFactory.AssertExpectations(t)
+		proxy.AssertExpectations(t)
+	})
+
+	t.Run("Should pick targets in proportion to their weights", func(t *testing.T) {
+		r := NewRandom(getWeightedTargets(), &MockedProxyFactory{})
+		r.randIntn = seededIntn()
+
+		counts := map[int]int{}
+
+		for i := 0; i < 7000; i++ {
+			target, err := r.next()
+
+			assert.Nil(t, err)
+			counts[target.Port]++
+		}
+
+		assert.InDelta(t, 5000, counts[8080], 250)
+		assert.InDelta(t, 1000, counts[8081], 150)
+		assert.InDelta(t, 1000, counts[8082], 150)
+	})
+
+	t.Run("Should skip unhealthy targets", func(t *testing.T) {
+		targets := getWeightedTargets()
+		targets[0].SetHealthy(false)
+
+		r := NewRandom(targets, &MockedProxyFactory{})
+		r.randIntn = seededIntn()
+
+		for i := 0; i < 100; i++ {
+			target, err := r.next()
+
+			assert.Nil(t, err)
+			assert.NotEqual(t, 8080, target.Port)
+		}
+	})
+
+	t.Run("Should return an error when no target is healthy", func(t *testing.T) {
+		targets := getTargets()
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(false)
+
+		_, err := NewRandom(targets, &MockedProxyFactory{}).next()
+
+		assert.Equal(t, errs.ErrNoHealthyTargets, err)
+	})
+}
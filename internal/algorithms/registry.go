@@ -0,0 +1,236 @@
+package algorithms
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+// DefaultAlgorithm is used by target groups that do not name one.
+const DefaultAlgorithm = "least-response-time"
+
+func init() {
+	alg.Register("round-robin", alg.NoOptions, func(targets []*lb.Target, proxyFactory proxy.ProxyFactory, _ struct{}) alg.Algorithm {
+		return NewRoundRobin(targets, proxyFactory)
+	})
+	alg.Register("weighted-round-robin", alg.NoOptions, func(targets []*lb.Target, proxyFactory proxy.ProxyFactory, _ struct{}) alg.Algorithm {
+		return NewWeightedRoundRobin(targets, proxyFactory)
+	})
+	alg.Register("least-connections", alg.NoOptions, func(targets []*lb.Target, proxyFactory proxy.ProxyFactory, _ struct{}) alg.Algorithm {
+		return NewLeastConnections(targets, proxyFactory)
+	})
+	alg.Register("random", alg.NoOptions, func(targets []*lb.Target, proxyFactory proxy.ProxyFactory, _ struct{}) alg.Algorithm {
+		return NewRandom(targets, proxyFactory)
+	})
+	alg.Register("least-response-time", decodeLeastResponseTimeOptions, func(targets []*lb.Target, proxyFactory proxy.ProxyFactory, opts NewLeastResponseTimeOptions) alg.Algorithm {
+		return NewLeastResponseTime(targets, proxyFactory, opts)
+	})
+	alg.Register("p2c", decodeP2COptions, func(targets []*lb.Target, proxyFactory proxy.ProxyFactory, opts NewP2COptions) alg.Algorithm {
+		return NewP2C(targets, proxyFactory, opts)
+	})
+	alg.Register("ewma", decodePeakEwmaOptions, func(targets []*lb.Target, proxyFactory proxy.ProxyFactory, opts NewPeakEwmaOptions) alg.Algorithm {
+		return NewPeakEwma(targets, proxyFactory, opts)
+	})
+	alg.RegisterHashing("consistent-hash", decodeConsistentHashOptions, func(targets []*lb.Target, proxyFactory proxy.ProxyFactory, opts NewConsistentHashOptions) alg.Algorithm {
+		return NewConsistentHash(targets, proxyFactory, opts)
+	})
+	alg.RegisterHashing("maglev", decodeMaglevOptions, func(targets []*lb.Target, proxyFactory proxy.ProxyFactory, opts NewMaglevOptions) alg.Algorithm {
+		return NewMaglev(targets, proxyFactory, opts)
+	})
+}
+
+func decodeLeastResponseTimeOptions(options alg.Options) (NewLeastResponseTimeOptions, error) {
+	// Without max-consecutive-requests, only the response times matter.
+	opts := NewLeastResponseTimeOptions{MaxConsecutiveRequests: math.MaxInt64}
+
+	if err := alg.CheckOptions(options, "max-consecutive-requests", "decay"); err != nil {
+		return opts, err
+	}
+
+	if maxConsecutiveRequests, ok := options["max-consecutive-requests"]; ok {
+		n, ok := maxConsecutiveRequests.(int)
+
+		if !ok || n <= 0 {
+			return opts, fmt.Errorf("max-consecutive-requests must be a positive integer")
+		}
+
+		opts.MaxConsecutiveRequests = int64(n)
+	}
+
+	decay, err := decodeDecay(options)
+
+	if err != nil {
+		return opts, err
+	}
+
+	opts.Decay = decay
+
+	return opts, nil
+}
+
+func decodeP2COptions(options alg.Options) (NewP2COptions, error) {
+	var opts NewP2COptions
+
+	if err := alg.CheckOptions(options, "compare", "decay"); err != nil {
+		return opts, err
+	}
+
+	compare, err := decodeString(options, "compare")
+
+	if err != nil {
+		return opts, err
+	}
+
+	switch compare {
+	case "", P2CCompareInFlight, P2CCompareEwma:
+		opts.Compare = compare
+	default:
+		return opts, fmt.Errorf("unknown p2c compare %q", compare)
+	}
+
+	decay, err := decodeDecay(options)
+
+	if err != nil {
+		return opts, err
+	}
+
+	opts.Decay = decay
+
+	return opts, nil
+}
+
+func decodePeakEwmaOptions(options alg.Options) (NewPeakEwmaOptions, error) {
+	if err := alg.CheckOptions(options, "decay"); err != nil {
+		return NewPeakEwmaOptions{}, err
+	}
+
+	decay, err := decodeDecay(options)
+
+	return NewPeakEwmaOptions{Decay: decay}, err
+}
+
+// decodeDecay reads the "decay" option, in seconds, of latency averages.
+func decodeDecay(options alg.Options) (time.Duration, error) {
+	decay, ok := options["decay"]
+
+	if !ok {
+		return 0, nil
+	}
+
+	switch n := decay.(type) {
+	case int:
+		if n > 0 {
+			return time.Duration(n) * time.Second, nil
+		}
+	case float64:
+		if n > 0 {
+			return time.Duration(n * float64(time.Second)), nil
+		}
+	}
+
+	return 0, fmt.Errorf("decay must be a positive number of seconds")
+}
+
+// decodeString reads the option called name, which is empty when missing.
+func decodeString(options alg.Options, name string) (string, error) {
+	value, ok := options[name]
+
+	if !ok {
+		return "", nil
+	}
+
+	s, ok := value.(string)
+
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", name)
+	}
+
+	return s, nil
+}
+
+func decodeHashKey(options alg.Options) (HashKey, error) {
+	key, err := decodeString(options, "hash-key")
+
+	if err != nil {
+		return HashKey{}, err
+	}
+
+	name, err := decodeString(options, "hash-key-name")
+
+	if err != nil {
+		return HashKey{}, err
+	}
+
+	switch key {
+	case "", HashKeyIp:
+		return HashKey{Source: HashKeyIp}, nil
+	case HashKeyHeader, HashKeyCookie, HashKeyQuery:
+		if name == "" {
+			return HashKey{}, fmt.Errorf("hash-key %s requires a hash-key-name", key)
+		}
+
+		return HashKey{Source: key, Name: name}, nil
+	}
+
+	return HashKey{}, fmt.Errorf("unknown hash-key %q", key)
+}
+
+func decodeConsistentHashOptions(options alg.Options) (NewConsistentHashOptions, error) {
+	var opts NewConsistentHashOptions
+
+	if err := alg.CheckOptions(options, "hash-key", "hash-key-name", "virtual-nodes"); err != nil {
+		return opts, err
+	}
+
+	key, err := decodeHashKey(options)
+
+	if err != nil {
+		return opts, err
+	}
+
+	opts.Key = key
+
+	if virtualNodes, ok := options["virtual-nodes"]; ok {
+		n, ok := virtualNodes.(int)
+
+		if !ok || n <= 0 {
+			return opts, fmt.Errorf("virtual-nodes must be a positive integer")
+		}
+
+		opts.VirtualNodes = n
+	}
+
+	return opts, nil
+}
+
+func decodeMaglevOptions(options alg.Options) (NewMaglevOptions, error) {
+	var opts NewMaglevOptions
+
+	if err := alg.CheckOptions(options, "hash-key", "hash-key-name", "table-size"); err != nil {
+		return opts, err
+	}
+
+	key, err := decodeHashKey(options)
+
+	if err != nil {
+		return opts, err
+	}
+
+	opts.Key = key
+
+	if tableSize, ok := options["table-size"]; ok {
+		n, ok := tableSize.(int)
+
+		if !ok || !IsPrime(n) {
+			return opts, fmt.Errorf("table-size must be a prime number")
+		}
+
+		opts.TableSize = n
+	}
+
+	return opts, nil
+}
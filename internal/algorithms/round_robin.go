@@ -2,10 +2,14 @@ package algorithms
 
 import (
 	"fmt"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"sync/atomic"
+	"time"
 
 	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
 	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
 
 	errs "github.com/joaosczip/go-lb/internal/errors"
@@ -15,6 +19,9 @@ type roundRobin struct {
 	current      atomic.Int64
 	targets      []*lb.Target
 	proxyFactory proxy.ProxyFactory
+	// randIntn draws the turns given away by ramping targets, from the
+	// global source when nil.
+	randIntn func(int) int
 }
 
 func NewRoundRobin(targets []*lb.Target, proxyFactory proxy.ProxyFactory) *roundRobin {
@@ -25,8 +32,13 @@ func NewRoundRobin(targets []*lb.Target, proxyFactory proxy.ProxyFactory) *round
 	}
 }
 
-func (r *roundRobin) Handle(w http.ResponseWriter, req *http.Request) error {
+func (r *roundRobin) next() (*lb.Target, error) {
 	numTargets := int64(len(r.targets))
+
+	if numTargets == 0 {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
 	currentIndex := r.current.Load()
 	currentTarget := r.targets[currentIndex]
 
@@ -41,14 +53,81 @@ func (r *roundRobin) Handle(w http.ResponseWriter, req *http.Request) error {
 		unhealthyTargets++
 
 		if int64(unhealthyTargets) == numTargets {
-			return errs.ErrNoHealthyTargets
+			return nil, errs.ErrNoHealthyTargets
 		}
 	}
 
-	proxy := r.proxyFactory.Create(currentTarget.Host, currentTarget.Port)
-	proxy.ServeHTTP(w, req)
+	// A target ramping up after becoming healthy gives its turn away with
+	// the probability of the share of its weight it does not have yet.
+	now := time.Now()
+
+	for i := int64(0); i < numTargets; i++ {
+		index := (currentIndex + i) % numTargets
+		target := r.targets[index]
+
+		if !target.IsHealthy() {
+			continue
+		}
+
+		if keepsTurn(target.SlowStartFactor(now), r.randIntn) {
+			currentIndex = index
+			currentTarget = target
+
+			break
+		}
+	}
 
 	r.current.Store((currentIndex + 1) % numTargets)
 
+	return currentTarget, nil
+}
+
+// slowStartDraws is how finely the turns of ramping targets are drawn.
+const slowStartDraws = 1000
+
+// keepsTurn reports whether a target ramping up with factor keeps its turn,
+// which it does with the probability of factor.
+func keepsTurn(factor float64, randIntn func(int) int) bool {
+	if factor >= 1 {
+		return true
+	}
+
+	if randIntn == nil {
+		randIntn = rand.IntN
+	}
+
+	return float64(randIntn(slowStartDraws)) < factor*slowStartDraws
+}
+
+func (r *roundRobin) Handle(w http.ResponseWriter, req *http.Request) error {
+	currentTarget, err := pickPinned(req, r.targets, itself, r.next)
+
+	if err != nil {
+		return err
+	}
+
+	if isUpgrade(req) {
+		currentTarget, err = acquireUpgrade(r.targets, currentTarget)
+
+		if err != nil {
+			return err
+		}
+
+		return serveUpgrade(w, req, currentTarget, r.proxyFactory)
+	}
+
+	proxy := r.proxyFactory.Create(currentTarget.Host, currentTarget.Port)
+	proxy.ServeHTTP(w, req)
+
 	return nil
 }
+
+func (r *roundRobin) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	currentTarget, err := r.next()
+
+	if err != nil {
+		return err
+	}
+
+	return proxy.ServeConn(conn, currentTarget.Host, currentTarget.Port)
+}
@@ -1,6 +1,7 @@
 package algorithms
 
 import (
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -22,6 +23,15 @@ func (m *MockedProxyFactory) Create(host string, port int) proxy.Proxy {
 	return args.Get(0).(proxy.Proxy)
 }
 
+type MockedConnProxy struct {
+	mock.Mock
+}
+
+func (m *MockedConnProxy) ServeConn(conn net.Conn, host string, port int) error {
+	args := m.Called(conn, host, port)
+	return args.Error(0)
+}
+
 type MockedProxy struct {
 	mock.Mock
 }
@@ -30,19 +40,26 @@ func (m *MockedProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	m.Called(w, r)
 }
 
+// healthyTargets marks every target healthy, as the health checks would.
+func healthyTargets(targets []*lb.Target) []*lb.Target {
+	for _, target := range targets {
+		target.SetHealthy(true)
+	}
+
+	return targets
+}
+
 func getTargets() []*lb.Target {
-	return []*lb.Target{
+	return healthyTargets([]*lb.Target{
 		{
-			Host:    "localhost",
-			Port:    8080,
-			Healthy: true,
+			Host: "localhost",
+			Port: 8080,
 		},
 		{
-			Host:    "localhost",
-			Port:    8081,
-			Healthy: true,
+			Host: "localhost",
+			Port: 8081,
 		},
-	}
+	})
 }
 
 func TestRoundRobin_Handle(t *testing.T) {
@@ -71,7 +88,7 @@ func TestRoundRobin_Handle(t *testing.T) {
 
 	t.Run("Should call the next target when the first one is unhealthy", func(t *testing.T) {
 		targets := getTargets()
-		targets[0].Healthy = false
+		targets[0].SetHealthy(false)
 
 		rr := NewRoundRobin(targets, proxyFactory)
 
@@ -93,8 +110,8 @@ func TestRoundRobin_Handle(t *testing.T) {
 
 	t.Run("Should return an error when all targets are unhealthy", func(t *testing.T) {
 		targets := getTargets()
-		targets[0].Healthy = false
-		targets[1].Healthy = false
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(false)
 
 		rr := NewRoundRobin(targets, proxyFactory)
 
@@ -109,4 +126,20 @@ func TestRoundRobin_Handle(t *testing.T) {
 		proxyFactory.AssertNotCalled(t, "Create")
 		proxy.AssertNotCalled(t, "ServeHTTP")
 	})
+
+	t.Run("Should relay connections to the targets in turn", func(t *testing.T) {
+		targets := getTargets()
+		rr := NewRoundRobin(targets, proxyFactory)
+		connProxy := &MockedConnProxy{}
+
+		client, _ := net.Pipe()
+
+		connProxy.On("ServeConn", client, "localhost", 8080).Return(nil).Once()
+		connProxy.On("ServeConn", client, "localhost", 8081).Return(nil).Once()
+
+		assert.Nil(t, rr.HandleConn(client, connProxy))
+		assert.Nil(t, rr.HandleConn(client, connProxy))
+
+		connProxy.AssertExpectations(t)
+	})
 }
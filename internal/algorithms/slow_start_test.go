@@ -0,0 +1,115 @@
+package algorithms
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+// getRampingTargets returns two targets, the second one having just become
+// healthy with a slow start so that it gets a tenth of its weight.
+func getRampingTargets() []*lb.Target {
+	targets := getTargets()
+
+	for _, target := range targets {
+		target.Weight = 1
+	}
+
+	targets[1].SlowStart = time.Hour
+	targets[1].SetHealthy(false)
+	targets[1].SetHealthy(true)
+
+	return targets
+}
+
+func TestSlowStart(t *testing.T) {
+	t.Run("Should ramp the weight of a target up after it becomes healthy", func(t *testing.T) {
+		target := &lb.Target{Weight: 4, SlowStart: 40 * time.Second}
+		target.SetHealthy(true)
+
+		now := target.HealthySince()
+
+		assert.InDelta(t, 0.1, target.SlowStartFactor(now), 0.001)
+		assert.InDelta(t, 0.5, target.SlowStartFactor(now.Add(20*time.Second)), 0.001)
+		assert.InDelta(t, 2, target.SlowStartWeight(now.Add(20*time.Second)), 0.001)
+		assert.Equal(t, 1.0, target.SlowStartFactor(now.Add(time.Minute)))
+
+		target.SlowStartAggression = 2
+		assert.InDelta(t, 0.707, target.SlowStartFactor(now.Add(20*time.Second)), 0.001)
+	})
+
+	t.Run("Should give the full weight without slow start", func(t *testing.T) {
+		target := &lb.Target{Weight: 4}
+		target.SetHealthy(true)
+
+		assert.Equal(t, 4.0, target.SlowStartWeight(time.Now()))
+	})
+
+	t.Run("Should give a ramping target its share in weighted round robin", func(t *testing.T) {
+		w := NewWeightedRoundRobin(getRampingTargets(), &MockedProxyFactory{})
+		counts := map[int]int{}
+
+		for i := 0; i < 1000; i++ {
+			target, err := w.next()
+
+			assert.Nil(t, err)
+			counts[target.Port]++
+		}
+
+		assert.InDelta(t, 91, counts[8081], 20)
+	})
+
+	t.Run("Should give a ramping target its share in round robin", func(t *testing.T) {
+		r := NewRoundRobin(getRampingTargets(), &MockedProxyFactory{})
+		r.randIntn = seededIntn()
+
+		counts := map[int]int{}
+
+		for i := 0; i < 2000; i++ {
+			target, err := r.next()
+
+			assert.Nil(t, err)
+			counts[target.Port]++
+		}
+
+		// The ramping target takes a tenth of its turns, so 1 request
+		// every 11.
+		assert.InDelta(t, 182, counts[8081], 40)
+	})
+
+	t.Run("Should prefer the target with the most weight to spare in least connections", func(t *testing.T) {
+		lc := NewLeastConnections(getRampingTargets(), &MockedProxyFactory{})
+		lc.targets[0].inFlight.Store(2)
+
+		target, err := lc.next()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 8080, target.Port)
+	})
+
+	t.Run("Should give a ramping target its share in least response time", func(t *testing.T) {
+		lrt := NewLeastResponseTime(getRampingTargets(), &MockedProxyFactory{}, NewLeastResponseTimeOptions{
+			MaxConsecutiveRequests: math.MaxInt64,
+		})
+		lrt.randIntn = seededIntn()
+		lrt.requestsCount.Store(1)
+		lrt.targets[0].avgResponseTime.Store(int64(100 * time.Millisecond))
+
+		counts := map[int]int{}
+
+		for i := 0; i < 1000; i++ {
+			target, err := lrt.next()
+
+			assert.Nil(t, err)
+			counts[target.Port]++
+		}
+
+		// The ramping target has no average yet, which alone would win it
+		// every request.
+		assert.InDelta(t, 100, counts[8081], 30)
+	})
+}
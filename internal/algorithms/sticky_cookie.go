@@ -0,0 +1,178 @@
+package algorithms
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+const StickyCookieName = "GOLBSTICKY"
+
+// stickyCookie pins a client to the target it was first sent to with a
+// cookie signed by the load balancer. The cookie carries the index of the
+// target in the target group and its expiry, so any instance sharing the
+// secret and the targets can honour it without clients learning the
+// addresses of the targets.
+type stickyCookie struct {
+	algorithm    alg.Algorithm
+	targets      []*lb.Target
+	indexes      map[string]int
+	proxyFactory proxy.ProxyFactory
+	secret       []byte
+	duration     time.Duration
+	now          func() time.Time
+}
+
+type NewStickyCookieOptions struct {
+	Secret   []byte
+	Duration time.Duration
+}
+
+// NewStickyCookie wraps the algorithm built by newAlgorithm. The algorithm
+// gets a proxy factory whose proxies set the cookie for the target they
+// reach, so it works with any algorithm without knowing how it picks.
+func NewStickyCookie(targets []*lb.Target, proxyFactory proxy.ProxyFactory, newAlgorithm func(proxy.ProxyFactory) (alg.Algorithm, error), opts NewStickyCookieOptions) (*stickyCookie, error) {
+	s := &stickyCookie{
+		targets:      targets,
+		indexes:      make(map[string]int, len(targets)),
+		proxyFactory: proxyFactory,
+		secret:       opts.Secret,
+		duration:     opts.Duration,
+		now:          time.Now,
+	}
+
+	for i, target := range targets {
+		s.indexes[net.JoinHostPort(target.Host, strconv.Itoa(target.Port))] = i
+	}
+
+	algorithm, err := newAlgorithm(&stickyCookieProxyFactory{sticky: s})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.algorithm = algorithm
+
+	return s, nil
+}
+
+func (s *stickyCookie) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *stickyCookie) cookieFor(host string, port int) *http.Cookie {
+	index := s.indexes[net.JoinHostPort(host, strconv.Itoa(port))]
+	expiresAt := s.now().Add(s.duration).Unix()
+
+	payload := fmt.Sprintf("%d|%d", index, expiresAt)
+
+	return &http.Cookie{
+		Name:     StickyCookieName,
+		Value:    payload + "." + s.sign(payload),
+		Path:     "/",
+		MaxAge:   int(s.duration.Seconds()),
+		HttpOnly: true,
+	}
+}
+
+// stickyTarget returns the target named by a valid cookie of req.
+func (s *stickyCookie) stickyTarget(req *http.Request) *lb.Target {
+	cookie, err := req.Cookie(StickyCookieName)
+
+	if err != nil {
+		return nil
+	}
+
+	payload, signature, ok := strings.Cut(cookie.Value, ".")
+
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(payload))) {
+		return nil
+	}
+
+	indexValue, expiry, ok := strings.Cut(payload, "|")
+
+	if !ok {
+		return nil
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+
+	if err != nil || s.now().Unix() >= expiresAt {
+		return nil
+	}
+
+	index, err := strconv.Atoi(indexValue)
+
+	if err != nil || index < 0 || index >= len(s.targets) {
+		return nil
+	}
+
+	return s.targets[index]
+}
+
+// Handle sends the request through the wrapped algorithm, pinned to the
+// target of its cookie if any, so the algorithm keeps accounting for it. The
+// algorithm picks another target when the pinned one is unhealthy or has no
+// room for an upgrade, and the client is pinned to that one instead.
+func (s *stickyCookie) Handle(w http.ResponseWriter, req *http.Request) error {
+	if target := s.stickyTarget(req); target != nil {
+		req = withPinnedTarget(req, target)
+	}
+
+	return s.algorithm.Handle(w, req)
+}
+
+func (s *stickyCookie) Unwrap() []alg.Algorithm {
+	return []alg.Algorithm{s.algorithm}
+}
+
+// HandleConn leaves connections to the wrapped algorithm, there is no cookie
+// to read from them.
+func (s *stickyCookie) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	return delegateConn(s.algorithm, conn, proxy)
+}
+
+// delegateConn hands a connection to the algorithm a wrapper delegates to.
+func delegateConn(algorithm alg.Algorithm, conn net.Conn, proxy alg.ConnProxy) error {
+	connAlgorithm, ok := algorithm.(alg.ConnAlgorithm)
+
+	if !ok {
+		return fmt.Errorf("algorithm does not support connections")
+	}
+
+	return connAlgorithm.HandleConn(conn, proxy)
+}
+
+type stickyCookieProxyFactory struct {
+	sticky *stickyCookie
+}
+
+func (f *stickyCookieProxyFactory) Create(host string, port int) proxy.Proxy {
+	return &stickyCookieProxy{
+		proxy:  f.sticky.proxyFactory.Create(host, port),
+		cookie: f.sticky.cookieFor(host, port),
+	}
+}
+
+type stickyCookieProxy struct {
+	proxy  proxy.Proxy
+	cookie *http.Cookie
+}
+
+func (p *stickyCookieProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	http.SetCookie(w, p.cookie)
+	p.proxy.ServeHTTP(w, req)
+}
@@ -0,0 +1,150 @@
+package algorithms
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestStickyCookie(targets []*lb.Target, proxyFactory proxy.ProxyFactory) (*stickyCookie, *roundRobin) {
+	var rr *roundRobin
+
+	sticky, _ := NewStickyCookie(targets, proxyFactory, func(proxyFactory proxy.ProxyFactory) (alg.Algorithm, error) {
+		rr = NewRoundRobin(targets, proxyFactory)
+		return rr, nil
+	}, NewStickyCookieOptions{
+		Secret:   []byte("secret"),
+		Duration: time.Hour,
+	})
+
+	return sticky, rr
+}
+
+func stickyRequest(cookie *http.Cookie) *http.Request {
+	r := httptest.NewRequest("GET", "http://localhost/", nil)
+
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	return r
+}
+
+func TestStickyCookie_Handle(t *testing.T) {
+	t.Run("Should set a cookie for the target picked by the wrapped algorithm", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		sticky, rr := newTestStickyCookie(getTargets(), proxyFactory)
+
+		proxyFactory.On("Create", "localhost", 8080).Return(proxy)
+		proxy.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+		w := httptest.NewRecorder()
+
+		assert.Nil(t, sticky.Handle(w, stickyRequest(nil)))
+		assert.Equal(t, int64(1), rr.current.Load())
+
+		cookies := w.Result().Cookies()
+
+		assert.Len(t, cookies, 1)
+		assert.Equal(t, StickyCookieName, cookies[0].Name)
+		assert.Equal(t, 3600, cookies[0].MaxAge)
+		assert.True(t, cookies[0].HttpOnly)
+	})
+
+	t.Run("Should send requests carrying the cookie to the same target", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		sticky, rr := newTestStickyCookie(getTargets(), proxyFactory)
+
+		proxyFactory.On("Create", "localhost", 8081).Return(proxy)
+		proxy.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+		cookie := sticky.cookieFor("localhost", 8081)
+
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, sticky.Handle(httptest.NewRecorder(), stickyRequest(cookie)))
+		}
+
+		assert.Equal(t, int64(0), rr.current.Load())
+		proxyFactory.AssertNumberOfCalls(t, "Create", 3)
+	})
+
+	t.Run("Should fall back to the wrapped algorithm when the cookie is not valid", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		targets := getTargets()
+		sticky, rr := newTestStickyCookie(targets, proxyFactory)
+
+		proxyFactory.On("Create", mock.Anything, mock.Anything).Return(proxy)
+		proxy.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+		tampered := sticky.cookieFor("localhost", 8081)
+		tampered.Value = tampered.Value[:len(tampered.Value)-2] + "xx"
+
+		expired := sticky.cookieFor("localhost", 8081)
+		sticky.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+		assert.Nil(t, sticky.Handle(httptest.NewRecorder(), stickyRequest(tampered)))
+		assert.Nil(t, sticky.Handle(httptest.NewRecorder(), stickyRequest(expired)))
+
+		sticky.now = time.Now
+		targets[1].SetHealthy(false)
+
+		assert.Nil(t, sticky.Handle(httptest.NewRecorder(), stickyRequest(sticky.cookieFor("localhost", 8081))))
+
+		// Each request went through the round robin: 8080, 8081, then 8080
+		// again since 8081 is unhealthy.
+		assert.Equal(t, int64(1), rr.current.Load())
+		proxyFactory.AssertNumberOfCalls(t, "Create", 3)
+	})
+
+	t.Run("Should not reveal the address of the target in the cookie", func(t *testing.T) {
+		sticky, _ := newTestStickyCookie(getTargets(), &MockedProxyFactory{})
+
+		cookie := sticky.cookieFor("localhost", 8081)
+
+		assert.NotContains(t, cookie.Value, "localhost")
+		assert.NotContains(t, cookie.Value, "8081")
+		assert.Same(t, sticky.targets[1], sticky.stickyTarget(stickyRequest(cookie)))
+	})
+
+	t.Run("Should count pinned requests in the wrapped algorithm", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		upstream := &MockedProxy{}
+
+		targets := getTargets()
+
+		var lc *leastConnections
+
+		sticky, err := NewStickyCookie(targets, proxyFactory, func(pf proxy.ProxyFactory) (alg.Algorithm, error) {
+			lc = NewLeastConnections(targets, pf)
+			return lc, nil
+		}, NewStickyCookieOptions{Secret: []byte("secret"), Duration: time.Hour})
+
+		assert.Nil(t, err)
+
+		var inFlight int64
+
+		proxyFactory.On("Create", "localhost", 8081).Return(upstream)
+		upstream.On("ServeHTTP", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+			inFlight = lc.targets[1].inFlight.Load()
+		}).Return()
+
+		assert.Nil(t, sticky.Handle(httptest.NewRecorder(), stickyRequest(sticky.cookieFor("localhost", 8081))))
+
+		assert.Equal(t, int64(1), inFlight)
+		assert.Equal(t, int64(0), lc.targets[1].inFlight.Load())
+		proxyFactory.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,68 @@
+package algorithms
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+// isUpgrade reports whether req asks to switch protocols, as WebSocket
+// handshakes do.
+func isUpgrade(req *http.Request) bool {
+	if req.Header.Get("Upgrade") == "" {
+		return false
+	}
+
+	for _, value := range req.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type upgradeTarget interface {
+	comparable
+	IsHealthy() bool
+	AcquireUpgradedConn() bool
+}
+
+// acquireUpgrade reserves an upgraded connection on selected or, when it
+// already holds its cap, on the next healthy target of targets with room, so
+// upgrades only fail once every healthy target is at its cap.
+func acquireUpgrade[T upgradeTarget](targets []T, selected T) (T, error) {
+	start := max(0, slices.Index(targets, selected))
+
+	for i := range targets {
+		target := targets[(start+i)%len(targets)]
+
+		if (target == selected || target.IsHealthy()) && target.AcquireUpgradedConn() {
+			return target, nil
+		}
+	}
+
+	var none T
+
+	return none, errs.ErrUpgradeLimit
+}
+
+// serveUpgrade proxies a request that switches protocols to a target that
+// acquired an upgraded connection for it. The proxy blocks for as long as
+// the client and the target talk, so the connection is counted against the
+// target's cap for that whole time.
+func serveUpgrade(w http.ResponseWriter, req *http.Request, target *lb.Target, proxyFactory proxy.ProxyFactory) error {
+	defer target.ReleaseUpgradedConn()
+
+	proxy := proxyFactory.Create(target.Host, target.Port)
+	proxy.ServeHTTP(w, req)
+
+	return nil
+}
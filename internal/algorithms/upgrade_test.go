@@ -0,0 +1,203 @@
+package algorithms
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+// newEchoUpgradeServer switches every request to an "echo" protocol that
+// writes back each line it reads.
+func newEchoUpgradeServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "Upgrade")
+		w.Header().Set("Upgrade", "echo")
+		w.WriteHeader(http.StatusSwitchingProtocols)
+
+		conn, rw, err := http.NewResponseController(w).Hijack()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		for {
+			line, err := rw.ReadString('\n')
+
+			if err != nil {
+				return
+			}
+
+			rw.WriteString(line)
+			rw.Flush()
+		}
+	}))
+}
+
+func TestUpgrade(t *testing.T) {
+	t.Run("Should hijack upgraded connections through least response time and keep them out of the average", func(t *testing.T) {
+		backend := newEchoUpgradeServer()
+		defer backend.Close()
+
+		addr := backend.Listener.Addr().(*net.TCPAddr)
+		target := &lb.Target{Host: "127.0.0.1", Port: addr.Port}
+		target.SetHealthy(true)
+
+		lrt := NewLeastResponseTime([]*lb.Target{target}, proxy.NewReverseProxyFactory(), NewLeastResponseTimeOptions{
+			MaxConsecutiveRequests: 10,
+		})
+
+		front := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, lrt.Handle(w, r))
+		}))
+		defer front.Close()
+
+		conn, err := net.Dial("tcp", front.Listener.Addr().String())
+		assert.NoError(t, err)
+
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n"))
+		assert.NoError(t, err)
+
+		reader := bufio.NewReader(conn)
+
+		res, err := http.ReadResponse(reader, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+
+		_, err = conn.Write([]byte("hello\n"))
+		assert.NoError(t, err)
+
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "hello\n", line)
+
+		assert.Equal(t, int64(1), target.UpgradedConns())
+
+		conn.Close()
+
+		assert.Eventually(t, func() bool {
+			return target.UpgradedConns() == 0
+		}, time.Second, 10*time.Millisecond)
+
+		assert.Equal(t, int64(0), lrt.targets[0].avgResponseTime.Load())
+		assert.Equal(t, int64(0), lrt.requestsCount.Load())
+	})
+
+	t.Run("Should refuse an upgrade when the target reached its cap", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		target := &lb.Target{Host: "localhost", Port: 8080, MaxUpgradedConns: 1}
+		target.SetHealthy(true)
+
+		rr := NewRoundRobin([]*lb.Target{target}, proxyFactory)
+
+		assert.True(t, target.AcquireUpgradedConn())
+
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+		r.Header.Set("Connection", "keep-alive, Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+
+		err := rr.Handle(httptest.NewRecorder(), r)
+
+		assert.ErrorIs(t, err, errs.ErrUpgradeLimit)
+		proxyFactory.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("Should send an upgrade to another healthy target when the picked one reached its cap", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		targets := getTargets()
+		targets = append(targets, &lb.Target{Host: "localhost", Port: 8082})
+		targets[0].MaxUpgradedConns = 1
+
+		assert.True(t, targets[0].AcquireUpgradedConn())
+
+		lc := NewLeastConnections(targets, proxyFactory)
+
+		w := httptest.NewRecorder()
+		r := newUpgradeRequest()
+
+		proxyFactory.On("Create", "localhost", 8081).Return(proxy)
+		proxy.On("ServeHTTP", w, r).Return()
+
+		assert.Nil(t, lc.Handle(w, r))
+
+		proxyFactory.AssertExpectations(t)
+		assert.Equal(t, int64(0), targets[1].UpgradedConns())
+	})
+
+	t.Run("Should move an upgrade to the next target on the ring when the key's target reached its cap", func(t *testing.T) {
+		targets := buildHashTargets(3)
+		ch := NewConsistentHash(targets, &MockedProxyFactory{}, NewConsistentHashOptions{})
+
+		expected, err := ch.next("user-42", false)
+		assert.Nil(t, err)
+
+		expected.MaxUpgradedConns = 1
+		assert.True(t, expected.AcquireUpgradedConn())
+
+		target, err := ch.next("user-42", true)
+
+		assert.Nil(t, err)
+		assert.NotSame(t, expected, target)
+		assert.Equal(t, int64(1), target.UpgradedConns())
+
+		for _, target := range targets {
+			target.MaxUpgradedConns = 1
+			target.AcquireUpgradedConn()
+		}
+
+		_, err = ch.next("user-42", true)
+
+		assert.ErrorIs(t, err, errs.ErrUpgradeLimit)
+	})
+
+	t.Run("Should leave the pinned target of a sticky cookie when it reached its cap", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		upstream := &MockedProxy{}
+
+		targets := getTargets()
+		targets[0].MaxUpgradedConns = 1
+
+		assert.True(t, targets[0].AcquireUpgradedConn())
+
+		sticky, err := NewStickyCookie(targets, proxyFactory, func(pf proxy.ProxyFactory) (alg.Algorithm, error) {
+			return NewLeastConnections(targets, pf), nil
+		}, NewStickyCookieOptions{Secret: []byte("secret"), Duration: time.Hour})
+
+		assert.Nil(t, err)
+
+		r := newUpgradeRequest()
+		r.AddCookie(sticky.cookieFor("localhost", 8080))
+
+		proxyFactory.On("Create", "localhost", 8081).Return(upstream)
+		upstream.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+		assert.Nil(t, sticky.Handle(httptest.NewRecorder(), r))
+
+		proxyFactory.AssertExpectations(t)
+		assert.Equal(t, int64(1), targets[0].UpgradedConns())
+	})
+}
+
+func newUpgradeRequest() *http.Request {
+	r := httptest.NewRequest("GET", "http://localhost/", nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+
+	return r
+}
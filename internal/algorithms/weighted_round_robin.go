@@ -0,0 +1,110 @@
+package algorithms
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+type weightedRoundRobinTarget struct {
+	*lb.Target
+	currentWeight float64
+}
+
+func (t *weightedRoundRobinTarget) lbTarget() *lb.Target {
+	return t.Target
+}
+
+// weightedRoundRobin is nginx's smooth weighted round robin: weights 5, 1, 1
+// give a a b a c a a rather than a burst of five requests to the first
+// target.
+type weightedRoundRobin struct {
+	targets      []*weightedRoundRobinTarget
+	proxyFactory proxy.ProxyFactory
+	mux          sync.Mutex
+}
+
+func NewWeightedRoundRobin(targets []*lb.Target, proxyFactory proxy.ProxyFactory) *weightedRoundRobin {
+	wrrTargets := make([]*weightedRoundRobinTarget, len(targets))
+
+	for i, target := range targets {
+		wrrTargets[i] = &weightedRoundRobinTarget{Target: target}
+	}
+
+	return &weightedRoundRobin{
+		targets:      wrrTargets,
+		proxyFactory: proxyFactory,
+	}
+}
+
+func (w *weightedRoundRobin) next() (*weightedRoundRobinTarget, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	var selected *weightedRoundRobinTarget
+	totalWeight := 0.0
+	now := time.Now()
+
+	for _, target := range w.targets {
+		if !target.IsHealthy() {
+			continue
+		}
+
+		weight := target.SlowStartWeight(now)
+
+		target.currentWeight += weight
+		totalWeight += weight
+
+		if selected == nil || target.currentWeight > selected.currentWeight {
+			selected = target
+		}
+	}
+
+	if selected == nil {
+		return nil, errs.ErrNoHealthyTargets
+	}
+
+	selected.currentWeight -= totalWeight
+
+	return selected, nil
+}
+
+func (w *weightedRoundRobin) Handle(rw http.ResponseWriter, req *http.Request) error {
+	currentTarget, err := pickPinned(req, w.targets, (*weightedRoundRobinTarget).lbTarget, w.next)
+
+	if err != nil {
+		return err
+	}
+
+	if isUpgrade(req) {
+		target, err := acquireUpgrade(w.targets, currentTarget)
+
+		if err != nil {
+			return err
+		}
+
+		return serveUpgrade(rw, req, target.Target, w.proxyFactory)
+	}
+
+	proxy := w.proxyFactory.Create(currentTarget.Host, currentTarget.Port)
+	proxy.ServeHTTP(rw, req)
+
+	return nil
+}
+
+func (w *weightedRoundRobin) HandleConn(conn net.Conn, proxy alg.ConnProxy) error {
+	currentTarget, err := w.next()
+
+	if err != nil {
+		return err
+	}
+
+	return proxy.ServeConn(conn, currentTarget.Host, currentTarget.Port)
+}
@@ -0,0 +1,137 @@
+package algorithms
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+	"github.com/stretchr/testify/assert"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+func getWeightedTargets() []*lb.Target {
+	return healthyTargets([]*lb.Target{
+		{Host: "localhost", Port: 8080, Weight: 5},
+		{Host: "localhost", Port: 8081, Weight: 1},
+		{Host: "localhost", Port: 8082, Weight: 1},
+	})
+}
+
+func TestWeightedRoundRobin_Handle(t *testing.T) {
+	t.Run("Should call the target picked by the smooth weighted round robin", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		wrr := NewWeightedRoundRobin(getWeightedTargets(), proxyFactory)
+
+		proxyFactory.On("Create", "localhost", 8080).Return(proxy)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost", nil)
+
+		proxy.On("ServeHTTP", w, r).Return()
+
+		assert.Nil(t, wrr.Handle(w, r))
+
+		proxyFactory.AssertExpectations(t)
+		proxy.AssertExpectations(t)
+	})
+
+	t.Run("Should interleave targets by weight without bursts", func(t *testing.T) {
+		wrr := NewWeightedRoundRobin(getWeightedTargets(), &MockedProxyFactory{})
+
+		var ports []int
+
+		for i := 0; i < 7; i++ {
+			target, err := wrr.next()
+
+			assert.Nil(t, err)
+			ports = append(ports, target.Port)
+		}
+
+		assert.Equal(t, []int{8080, 8080, 8081, 8080, 8082, 8080, 8080}, ports)
+	})
+
+	t.Run("Should share the traffic of an unhealthy target between the others", func(t *testing.T) {
+		targets := getWeightedTargets()
+		targets[0].SetHealthy(false)
+
+		wrr := NewWeightedRoundRobin(targets, &MockedProxyFactory{})
+
+		counts := map[int]int{}
+
+		for i := 0; i < 10; i++ {
+			target, err := wrr.next()
+
+			assert.Nil(t, err)
+			counts[target.Port]++
+		}
+
+		assert.Equal(t, map[int]int{8081: 5, 8082: 5}, counts)
+	})
+
+	t.Run("Should return an error when no target is healthy", func(t *testing.T) {
+		targets := getWeightedTargets()
+
+		for _, target := range targets {
+			target.SetHealthy(false)
+		}
+
+		wrr := NewWeightedRoundRobin(targets, &MockedProxyFactory{})
+
+		_, err := wrr.next()
+
+		assert.Equal(t, errs.ErrNoHealthyTargets, err)
+	})
+}
+
+func TestWeights(t *testing.T) {
+	t.Run("Should compare in-flight requests relative to the weights in least connections", func(t *testing.T) {
+		lc := NewLeastConnections(getWeightedTargets(), &MockedProxyFactory{})
+		lc.targets[0].inFlight.Store(4)
+		lc.targets[1].inFlight.Store(1)
+		lc.targets[2].inFlight.Store(1)
+
+		target, err := lc.next()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 8080, target.Port)
+	})
+
+	t.Run("Should divide the average response time by the weight in least response time", func(t *testing.T) {
+		targets := getWeightedTargets()
+
+		lrt := NewLeastResponseTime(targets[:2], &MockedProxyFactory{}, NewLeastResponseTimeOptions{MaxConsecutiveRequests: 10})
+		lrt.requestsCount.Store(2)
+		lrt.targets[0].avgResponseTime.Store(400)
+		lrt.targets[1].avgResponseTime.Store(100)
+
+		target, err := lrt.next()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 8080, target.Port)
+	})
+
+	t.Run("Should give heavier targets a matching share of the hashing algorithms", func(t *testing.T) {
+		targets := getWeightedTargets()
+
+		ch := NewConsistentHash(targets, &MockedProxyFactory{}, NewConsistentHashOptions{})
+		chCounts := map[int]int{}
+
+		for _, port := range assignKeys(t, ch, 7000) {
+			chCounts[port]++
+		}
+
+		assert.InDelta(t, 5000, chCounts[8080], 500)
+
+		m := NewMaglev(targets, &MockedProxyFactory{}, NewMaglevOptions{})
+		maglevCounts := map[int]int{}
+
+		for _, target := range m.currentTable().entries {
+			maglevCounts[target.Port]++
+		}
+
+		assert.InDelta(t, DefaultMaglevTableSize*5/7, maglevCounts[8080], 10)
+	})
+}
@@ -0,0 +1,84 @@
+package algorithms
+
+import (
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+// NewZoneAware keeps the traffic in the zone of the load balancer as long as
+// the zone can take it. Load balancers are assumed to be spread evenly over
+// the zones of the targets, so the local zone can take its share of the
+// traffic when it holds at least that share of the healthy capacity. When
+// it holds less, it takes in proportion and the rest spills over to the
+// other zones by their healthy capacity.
+func NewZoneAware(targets []*lb.Target, localZone string, newAlgorithm func([]*lb.Target) (alg.Algorithm, error)) (*partitioned, error) {
+	z, err := newPartitioned(targets, func(target *lb.Target) string {
+		return target.Zone
+	}, newAlgorithm)
+
+	if err != nil {
+		return nil, err
+	}
+
+	z.loads = func() []int {
+		return zoneLoads(z.partitions, localZone)
+	}
+
+	return z, nil
+}
+
+func zoneCapacity(zone *partition) int {
+	capacity := 0
+
+	for _, target := range zone.targets {
+		if target.IsHealthy() {
+			capacity += target.EffectiveWeight()
+		}
+	}
+
+	return capacity
+}
+
+// zoneLoads returns the share of the traffic, in basis points, of each zone.
+func zoneLoads(zones []*partition, localZone string) []int {
+	loads := make([]int, len(zones))
+	capacities := make([]int, len(zones))
+	total := 0
+
+	for i, zone := range zones {
+		capacities[i] = zoneCapacity(zone)
+		total += capacities[i]
+	}
+
+	if total == 0 {
+		return loads
+	}
+
+	local := -1
+	remaining := 10000
+	remote := total
+
+	for i, zone := range zones {
+		if zone.targets[0].Zone != localZone {
+			continue
+		}
+
+		// The local zone is expected to take 1/len(zones) of the traffic.
+		local = i
+		loads[i] = min(10000, capacities[i]*10000*len(zones)/total)
+		remaining -= loads[i]
+		remote -= capacities[i]
+	}
+
+	if remote == 0 {
+		return loads
+	}
+
+	for i := range zones {
+		if i != local {
+			loads[i] = remaining * capacities[i] / remote
+		}
+	}
+
+	return loads
+}
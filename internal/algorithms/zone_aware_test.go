@@ -0,0 +1,110 @@
+package algorithms
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	lb "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+)
+
+func getZonedTargets() []*lb.Target {
+	zones := []string{"a", "a", "b", "b", "c", "c"}
+	targets := make([]*lb.Target, len(zones))
+
+	for i, zone := range zones {
+		targets[i] = &lb.Target{
+			Host:   "localhost",
+			Port:   8080 + i,
+			Weight: 1,
+			Zone:   zone,
+		}
+	}
+
+	return healthyTargets(targets)
+}
+
+func newZonedRoundRobin(targets []*lb.Target, localZone string, proxyFactory *MockedProxyFactory) *partitioned {
+	z, _ := NewZoneAware(targets, localZone, func(zoneTargets []*lb.Target) (alg.Algorithm, error) {
+		return NewRoundRobin(zoneTargets, proxyFactory), nil
+	})
+
+	return z
+}
+
+func TestZoneAware_Handle(t *testing.T) {
+	t.Run("Should keep the traffic in the local zone while it holds its share of the capacity", func(t *testing.T) {
+		z := newZonedRoundRobin(getZonedTargets(), "a", &MockedProxyFactory{})
+
+		assert.Equal(t, []int{10000, 0, 0}, z.loads())
+	})
+
+	t.Run("Should spill over to the other zones in proportion to their capacity", func(t *testing.T) {
+		targets := getZonedTargets()
+		targets[0].SetHealthy(false)
+		targets[4].SetHealthy(false)
+
+		z := newZonedRoundRobin(targets, "a", &MockedProxyFactory{})
+
+		// a holds 1 of 4 healthy targets, a quarter against the third it is
+		// expected to take.
+		assert.Equal(t, []int{7500, 1666, 833}, z.loads())
+	})
+
+	t.Run("Should send everything to the other zones when the local zone is down", func(t *testing.T) {
+		targets := getZonedTargets()
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(false)
+
+		z := newZonedRoundRobin(targets, "a", &MockedProxyFactory{})
+
+		assert.Equal(t, []int{0, 5000, 5000}, z.loads())
+	})
+
+	t.Run("Should spread the traffic by capacity when the local zone has no targets", func(t *testing.T) {
+		targets := getZonedTargets()
+		targets[2].Weight = 3
+
+		z := newZonedRoundRobin(targets, "d", &MockedProxyFactory{})
+
+		assert.Equal(t, []int{2500, 5000, 2500}, z.loads())
+	})
+
+	t.Run("Should handle the request with the algorithm of the picked zone", func(t *testing.T) {
+		proxyFactory := &MockedProxyFactory{}
+		proxy := &MockedProxy{}
+
+		z := newZonedRoundRobin(getZonedTargets(), "b", proxyFactory)
+		z.randIntn = seededIntn()
+
+		proxyFactory.On("Create", "localhost", 8082).Return(proxy)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "http://localhost", nil)
+
+		proxy.On("ServeHTTP", w, req).Return()
+
+		assert.Nil(t, z.Handle(w, req))
+
+		proxyFactory.AssertExpectations(t)
+		proxy.AssertExpectations(t)
+	})
+
+	t.Run("Should return an error when no zone has healthy targets", func(t *testing.T) {
+		targets := getZonedTargets()
+
+		for _, target := range targets {
+			target.SetHealthy(false)
+		}
+
+		z := newZonedRoundRobin(targets, "a", &MockedProxyFactory{})
+
+		err := z.Handle(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost", nil))
+
+		assert.ErrorIs(t, err, errs.ErrNoHealthyTargets)
+	})
+}
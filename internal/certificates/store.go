@@ -0,0 +1,216 @@
+package certificates
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type KeyPairFiles struct {
+	CertFile string
+	KeyFile  string
+}
+
+type keyPair struct {
+	files       KeyPairFiles
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// Store holds the certificates served by a TLS listener and picks one per
+// handshake based on the SNI sent by the client.
+type Store struct {
+	keyPairs []*keyPair
+	mux      sync.RWMutex
+}
+
+// NewStore loads every key pair from disk. When reloadInterval is positive,
+// the files are polled at that interval and reloaded when they change.
+func NewStore(files []KeyPairFiles, reloadInterval time.Duration) (*Store, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("at least one certificate is required")
+	}
+
+	store := &Store{}
+
+	for _, f := range files {
+		pair := &keyPair{files: f}
+
+		if err := pair.load(); err != nil {
+			return nil, err
+		}
+
+		store.keyPairs = append(store.keyPairs, pair)
+	}
+
+	if reloadInterval > 0 {
+		go store.watch(reloadInterval)
+	}
+
+	return store, nil
+}
+
+func (p *keyPair) load() error {
+	certInfo, err := os.Stat(p.files.CertFile)
+
+	if err != nil {
+		return fmt.Errorf("could not stat certificate %s: %v", p.files.CertFile, err)
+	}
+
+	keyInfo, err := os.Stat(p.files.KeyFile)
+
+	if err != nil {
+		return fmt.Errorf("could not stat key %s: %v", p.files.KeyFile, err)
+	}
+
+	certificate, err := tls.LoadX509KeyPair(p.files.CertFile, p.files.KeyFile)
+
+	if err != nil {
+		return fmt.Errorf("could not load certificate %s: %v", p.files.CertFile, err)
+	}
+
+	p.certificate = &certificate
+	p.certModTime = certInfo.ModTime()
+	p.keyModTime = keyInfo.ModTime()
+
+	return nil
+}
+
+func (p *keyPair) changed() bool {
+	certInfo, err := os.Stat(p.files.CertFile)
+
+	if err != nil {
+		return false
+	}
+
+	keyInfo, err := os.Stat(p.files.KeyFile)
+
+	if err != nil {
+		return false
+	}
+
+	return !certInfo.ModTime().Equal(p.certModTime) || !keyInfo.ModTime().Equal(p.keyModTime)
+}
+
+// Reload reloads the key pairs whose files changed on disk. A key pair that
+// fails to load keeps serving its previous certificate.
+func (s *Store) Reload() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	var firstErr error
+
+	for _, pair := range s.keyPairs {
+		if !pair.changed() {
+			continue
+		}
+
+		if err := pair.load(); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+
+		log.Printf("reloaded certificate %s", pair.files.CertFile)
+	}
+
+	return firstErr
+}
+
+func (s *Store) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+
+		if err := s.Reload(); err != nil {
+			log.Printf("could not reload certificates: %v", err)
+		}
+	}
+}
+
+// GetCertificate returns the first certificate valid for the server name
+// requested by the client, falling back to the first configured one.
+func (s *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	for _, pair := range s.keyPairs {
+		if hello.SupportsCertificate(pair.certificate) == nil {
+			return pair.certificate, nil
+		}
+	}
+
+	return s.keyPairs[0].certificate, nil
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func ParseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return tls.VersionTLS12, nil
+	}
+
+	v, ok := tlsVersions[version]
+
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q", version)
+	}
+
+	return v, nil
+}
+
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	suitesByName := make(map[string]uint16)
+
+	for _, suite := range tls.CipherSuites() {
+		suitesByName[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, len(names))
+
+	for i, name := range names {
+		id, ok := suitesByName[name]
+
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+
+		ids[i] = id
+	}
+
+	return ids, nil
+}
+
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA bundle %s: %v", path, err)
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,147 @@
+package certificates
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKeyPair(t *testing.T, dir, name string, dnsNames []string) KeyPairFiles {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	files := KeyPairFiles{
+		CertFile: filepath.Join(dir, name+".pem"),
+		KeyFile:  filepath.Join(dir, name+"-key.pem"),
+	}
+
+	assert.NoError(t, os.WriteFile(files.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(files.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return files
+}
+
+func leafDNSNames(t *testing.T, certificate *tls.Certificate) []string {
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	assert.NoError(t, err)
+
+	return leaf.DNSNames
+}
+
+func clientHello(serverName string) *tls.ClientHelloInfo {
+	return &tls.ClientHelloInfo{
+		ServerName:        serverName,
+		SupportedVersions: []uint16{tls.VersionTLS13, tls.VersionTLS12},
+		SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		SupportedCurves:   []tls.CurveID{tls.CurveP256},
+		SupportedPoints:   []uint8{0},
+		CipherSuites:      []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}
+}
+
+func TestStore_GetCertificate(t *testing.T) {
+	dir := t.TempDir()
+
+	example := writeKeyPair(t, dir, "example", []string{"example.com"})
+	wildcard := writeKeyPair(t, dir, "wildcard", []string{"*.internal.example.com"})
+
+	store, err := NewStore([]KeyPairFiles{example, wildcard}, 0)
+	assert.NoError(t, err)
+
+	t.Run("Should select the certificate matching the SNI", func(t *testing.T) {
+		certificate, err := store.GetCertificate(clientHello("api.internal.example.com"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"*.internal.example.com"}, leafDNSNames(t, certificate))
+	})
+
+	t.Run("Should fall back to the first certificate when no SNI matches", func(t *testing.T) {
+		certificate, err := store.GetCertificate(clientHello("unknown.org"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"example.com"}, leafDNSNames(t, certificate))
+	})
+}
+
+func TestStore_Reload(t *testing.T) {
+	t.Run("Should reload a certificate after its files change", func(t *testing.T) {
+		dir := t.TempDir()
+		files := writeKeyPair(t, dir, "site", []string{"old.example.com"})
+
+		store, err := NewStore([]KeyPairFiles{files}, 0)
+		assert.NoError(t, err)
+
+		writeKeyPair(t, dir, "site", []string{"new.example.com"})
+
+		future := time.Now().Add(time.Minute)
+		assert.NoError(t, os.Chtimes(files.CertFile, future, future))
+		assert.NoError(t, os.Chtimes(files.KeyFile, future, future))
+
+		assert.NoError(t, store.Reload())
+
+		certificate, err := store.GetCertificate(clientHello("new.example.com"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"new.example.com"}, leafDNSNames(t, certificate))
+	})
+
+	t.Run("Should keep serving the previous certificate when the new one is invalid", func(t *testing.T) {
+		dir := t.TempDir()
+		files := writeKeyPair(t, dir, "site", []string{"old.example.com"})
+
+		store, err := NewStore([]KeyPairFiles{files}, 0)
+		assert.NoError(t, err)
+
+		assert.NoError(t, os.WriteFile(files.CertFile, []byte("garbage"), 0600))
+
+		future := time.Now().Add(time.Minute)
+		assert.NoError(t, os.Chtimes(files.CertFile, future, future))
+
+		assert.Error(t, store.Reload())
+
+		certificate, err := store.GetCertificate(clientHello("old.example.com"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"old.example.com"}, leafDNSNames(t, certificate))
+	})
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	t.Run("Should resolve cipher suites by name", func(t *testing.T) {
+		ids, err := ParseCipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, ids)
+	})
+
+	t.Run("Should reject unknown cipher suites", func(t *testing.T) {
+		_, err := ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+
+		assert.EqualError(t, err, `unknown or insecure cipher suite "TLS_RSA_WITH_RC4_128_SHA"`)
+	})
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/joaosczip/go-lb/internal/algorithms"
 	"github.com/joaosczip/go-lb/internal/proxy"
@@ -13,8 +14,12 @@ import (
 )
 
 type LBConfig struct {
-	Port         int           `yaml:"port"`
-	TargetGroups []TargetGroup `yaml:"target-groups"`
+	Port          int           `yaml:"port"`
+	Zone          string        `yaml:"zone,omitempty"`
+	TargetGroups  []TargetGroup `yaml:"target-groups"`
+	Rules         []Rule        `yaml:"rules,omitempty"`
+	DefaultAction *Action       `yaml:"default-action,omitempty"`
+	Listeners     []Listener    `yaml:"listeners,omitempty"`
 }
 
 type Algorithm struct {
@@ -23,23 +28,43 @@ type Algorithm struct {
 }
 
 type TargetGroup struct {
-	Name        string      `yaml:"name"`
-	Algorithm   Algorithm   `yaml:"algorithm"`
-	HealthCheck HealthCheck `yaml:"health-check"`
-	Targets     []Target    `yaml:"targets"`
+	Name                   string            `yaml:"name"`
+	Protocol               string            `yaml:"protocol,omitempty"`
+	TLS                    *TargetTLS        `yaml:"tls,omitempty"`
+	ProxyProtocol          string            `yaml:"proxy-protocol,omitempty"`
+	ProtocolVersion        string            `yaml:"protocol-version,omitempty"`
+	MaxUpgradedConnections int64             `yaml:"max-upgraded-connections,omitempty"`
+	Algorithm              Algorithm         `yaml:"algorithm"`
+	Stickiness             *TargetStickiness `yaml:"stickiness,omitempty"`
+	Failover               *Failover         `yaml:"failover,omitempty"`
+	SlowStart              *SlowStart        `yaml:"slow-start,omitempty"`
+	HealthCheck            HealthCheck       `yaml:"health-check"`
+	Targets                []Target          `yaml:"targets"`
 }
 
 type HealthCheck struct {
+	Type             string `yaml:"type,omitempty"`
 	Interval         int    `yaml:"interval"`
 	Timeout          int    `yaml:"timeout"`
 	FailureThreshold int    `yaml:"failure-threshold"`
 	HealthyThreshold int    `yaml:"healthy-threshold"`
 	Path             string `yaml:"path"`
+	GrpcService      string `yaml:"grpc-service,omitempty"`
+}
+
+// SlowStart ramps the weight of a target up over Duration seconds after it
+// becomes healthy.
+type SlowStart struct {
+	Duration   int     `yaml:"duration"`
+	Aggression float64 `yaml:"aggression,omitempty"`
 }
 
 type Target struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Weight   int    `yaml:"weight,omitempty"`
+	Priority int    `yaml:"priority,omitempty"`
+	Zone     string `yaml:"zone,omitempty"`
 }
 
 type ConfigLoader struct {
@@ -58,14 +83,44 @@ func NewConfigLoader(configFilePath string, httpClient *http.Client, proxyFactor
 	}
 }
 
-func (c *ConfigLoader) getAlgorithm(targets []*targetgroup.Target, algConfig Algorithm) alg.Algorithm {
-	if algConfig.Type == "round-robin" {
-		return algorithms.NewRoundRobin(targets, c.proxyFactory)
+// getAlgorithm builds the algorithm registered under the configured type.
+func (c *ConfigLoader) getAlgorithm(targets []*targetgroup.Target, algConfig Algorithm, proxyFactory proxy.ProxyFactory) (alg.Algorithm, error) {
+	name := algConfig.Type
+
+	if name == "" {
+		name = algorithms.DefaultAlgorithm
+	}
+
+	return alg.New(name, targets, proxyFactory, algConfig.Options)
+}
+
+func getHealthCheckType(tg TargetGroup) (string, error) {
+	switch tg.HealthCheck.Type {
+	case "":
+		// UDP has no handshake to probe, and a UDP-only target never accepts a
+		// tcp check, so udp target groups must say how they are checked.
+		if tg.Protocol == "udp" {
+			return "", fmt.Errorf("udp target groups require a health check type, tcp or http")
+		}
+
+		if tg.Protocol == "tcp" {
+			return "tcp", nil
+		}
+
+		return "http", nil
+	case "http", "tcp":
+		return tg.HealthCheck.Type, nil
+	case "grpc":
+		// The gRPC health checking protocol runs over HTTP/2, so the targets must
+		// be reached either with h2c or over TLS.
+		if tg.ProtocolVersion == "h2c" || (tg.Protocol == "https" && tg.ProtocolVersion != "http1") {
+			return "grpc", nil
+		}
+
+		return "", fmt.Errorf("health check type grpc requires protocol-version h2c or protocol https")
 	}
-	
-	return algorithms.NewLeastResponseTime(targets, c.proxyFactory, algorithms.NewLeastResponseTimeOptions{
-		MaxConsecutiveRequests: int64(algConfig.Options["max-consecutive-requests"].(int)),
-	})
+
+	return "", fmt.Errorf("unknown health check type %q", tg.HealthCheck.Type)
 }
 
 func (c *ConfigLoader) Load() (*lb.LoadBalancer, error) {
@@ -84,30 +139,99 @@ func (c *ConfigLoader) Load() (*lb.LoadBalancer, error) {
 
 	var targetGroups []*targetgroup.TargetGroup
 
+	// Rules and listeners reference target groups by name, so a duplicate
+	// would silently replace the group they were written against.
+	targetGroupNames := make(map[string]bool, len(config.TargetGroups))
+
 	for _, tg := range config.TargetGroups {
+		if targetGroupNames[tg.Name] {
+			return nil, fmt.Errorf("duplicate target group name %q", tg.Name)
+		}
+
+		targetGroupNames[tg.Name] = true
+
+		tgUpstream, err := c.getUpstream(tg)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not build target group %s: %v", tg.Name, err)
+		}
+
+		if tg.MaxUpgradedConnections < 0 {
+			return nil, fmt.Errorf("could not build target group %s: max-upgraded-connections must not be negative", tg.Name)
+		}
+
+		if tg.SlowStart != nil && (tg.SlowStart.Duration <= 0 || tg.SlowStart.Aggression < 0) {
+			return nil, fmt.Errorf("could not build target group %s: slow-start requires a positive duration and a non-negative aggression", tg.Name)
+		}
+
 		var targets []*targetgroup.Target
 
 		for _, target := range tg.Targets {
-			targets = append(targets, targetgroup.NewTarget(target.Host, target.Port))
+			if target.Weight < 0 {
+				return nil, fmt.Errorf("could not build target group %s: target %s:%d has a negative weight", tg.Name, target.Host, target.Port)
+			}
+
+			if target.Priority < 0 {
+				return nil, fmt.Errorf("could not build target group %s: target %s:%d has a negative priority", tg.Name, target.Host, target.Port)
+			}
+
+			t := targetgroup.NewTarget(target.Host, target.Port)
+			t.MaxUpgradedConns = tg.MaxUpgradedConnections
+			t.Priority = target.Priority
+			t.Zone = target.Zone
+
+			if tg.SlowStart != nil {
+				t.SlowStart = time.Duration(tg.SlowStart.Duration) * time.Second
+				t.SlowStartAggression = tg.SlowStart.Aggression
+			}
+
+			if target.Weight > 0 {
+				t.Weight = target.Weight
+			}
+
+			targets = append(targets, t)
+		}
+
+		healthCheckType, err := getHealthCheckType(tg)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not build target group %s: %v", tg.Name, err)
 		}
 
 		healthCheckConfig := targetgroup.NewHealthCheckConfig(
 			targetgroup.HealthCheckConfigParams{
+				Type:             healthCheckType,
 				IntervalInSec:    tg.HealthCheck.Interval,
 				TimeoutInSec:     tg.HealthCheck.Timeout,
 				FailureThreshold: tg.HealthCheck.FailureThreshold,
 				HealthyThreshold: tg.HealthCheck.HealthyThreshold,
 				Path:             tg.HealthCheck.Path,
-				HttpClient:       c.httpClient,
+				GrpcService:      tg.HealthCheck.GrpcService,
+				Scheme:           tgUpstream.scheme,
+				HttpClient:       tgUpstream.httpClient,
 			},
 		)
 
+		algorithm, err := c.getTargetGroupAlgorithm(tg, config.Zone, targets, tgUpstream.proxyFactory)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not build target group %s: %v", tg.Name, err)
+		}
+
 		targetGroups = append(targetGroups, targetgroup.NewTargetGroup(targetgroup.NewTargetGroupParams{
+			Name:              tg.Name,
+			Protocol:          tgUpstream.protocol,
 			Targets:           targets,
 			HealthCheckConfig: healthCheckConfig,
-			Algorithm:         c.getAlgorithm(targets, tg.Algorithm),
+			Algorithm:         algorithm,
 		}))
 	}
 
-	return lb.NewLoadBalancer(targetGroups, config.Port), nil
+	listeners, err := buildListeners(config, targetGroups)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not build listeners: %v", err)
+	}
+
+	return lb.NewLoadBalancer(targetGroups, listeners), nil
 }
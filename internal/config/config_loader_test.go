@@ -2,17 +2,38 @@ package config
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/joaosczip/go-lb/internal/algorithms"
 	"github.com/joaosczip/go-lb/internal/proxy"
+	"github.com/joaosczip/go-lb/pkg/lb"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
 	"github.com/joaosczip/go-lb/pkg/lb/targetgroup"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// httpOnlyAlgorithm handles requests but not connections.
+type httpOnlyAlgorithm struct{}
+
+func (h *httpOnlyAlgorithm) Handle(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func init() {
+	alg.Register("http-only", alg.NoOptions, func(targets []*targetgroup.Target, proxyFactory proxy.ProxyFactory, _ struct{}) alg.Algorithm {
+		return &httpOnlyAlgorithm{}
+	})
+	alg.RegisterHashing("external-hash", alg.NoOptions, func(targets []*targetgroup.Target, proxyFactory proxy.ProxyFactory, _ struct{}) alg.Algorithm {
+		return &httpOnlyAlgorithm{}
+	})
+}
+
 type FileReaderMock struct {
 	mock.Mock
 }
@@ -31,6 +52,14 @@ func (m *ProxyFactoryMock) Create(host string, port int) proxy.Proxy {
 	return args.Get(0).(proxy.Proxy)
 }
 
+type ProxyMock struct {
+	mock.Mock
+}
+
+func (m *ProxyMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+}
+
 type TestSetup struct {
 	fileReader   *FileReaderMock
 	proxyFactory *ProxyFactoryMock
@@ -101,15 +130,16 @@ func TestConfigLoader_Load(t *testing.T) {
 		}))
 
 		assert.Equal(t, targetGroups[0].Targets, []*targetgroup.Target{
-			{Host: "localhost", Port: 8080},
-			{Host: "localhost", Port: 8081},
+			{Host: "localhost", Port: 8080, Weight: 1},
+			{Host: "localhost", Port: 8081, Weight: 1},
 		})
 		assert.Equal(t, targetGroups[1].Targets, []*targetgroup.Target{
-			{Host: "localhost", Port: 8082},
-			{Host: "localhost", Port: 8083},
+			{Host: "localhost", Port: 8082, Weight: 1},
+			{Host: "localhost", Port: 8083, Weight: 1},
 		})
 
 		assert.Equal(t, targetGroups[0].HealthCheckConfig, &targetgroup.HealthCheckConfig{
+			Type:             "http",
 			Interval:         1,
 			Timeout:          2,
 			FailureThreshold: 3,
@@ -118,6 +148,7 @@ func TestConfigLoader_Load(t *testing.T) {
 			HttpClient:       &testSetup.httpClient,
 		})
 		assert.Equal(t, targetGroups[1].HealthCheckConfig, &targetgroup.HealthCheckConfig{
+			Type:             "http",
 			Interval:         1,
 			Timeout:          2,
 			FailureThreshold: 3,
@@ -126,4 +157,1399 @@ func TestConfigLoader_Load(t *testing.T) {
 			HttpClient:       &testSetup.httpClient,
 		})
 	})
+
+	t.Run("Should compile the listener rules into a rule table", func(t *testing.T) {
+		testSetup := setup()
+
+		mockedYamlConfig, err := os.ReadFile("../../test/fixtures/mocked_config_file.yaml")
+
+		assert.NoError(t, err)
+
+		testSetup.fileReader.On("Read", "config.yaml").Return(mockedYamlConfig, nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		targetGroups := loadBalancer.TargetGroups
+
+		assert.Equal(t, "test", targetGroups[0].Name)
+		assert.Equal(t, "test-2", targetGroups[1].Name)
+
+		assert.Len(t, loadBalancer.Listeners[0].Rules.Rules, 1)
+
+		apiRequest := httptest.NewRequest("GET", "http://localhost/api/users", nil)
+		assert.Equal(t, lb.NewForwardAction(targetGroups[1]), loadBalancer.Listeners[0].Rules.Match(apiRequest))
+
+		otherRequest := httptest.NewRequest("DELETE", "http://localhost/api/users", nil)
+		assert.Equal(t, lb.NewForwardAction(targetGroups[0]), loadBalancer.Listeners[0].Rules.Match(otherRequest))
+	})
+
+	t.Run("Should return an error when a rule references an unknown target group", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+port: 9000
+target-groups:
+  - name: test
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+rules:
+  - priority: 1
+    conditions:
+      - field: path-prefix
+        values: ["/"]
+    action:
+      type: forward
+      target-group: missing
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, `could not build listeners: could not build listener 9000: could not build rules: could not build rule 1: unknown target group "missing"`)
+	})
+
+	t.Run("Should compile header, query-string, source-ip and compound conditions", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+port: 9000
+target-groups:
+  - name: stable
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+  - name: beta
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+rules:
+  - priority: 1
+    conditions:
+      - any:
+          - field: http-header
+            name: X-Beta
+            values: ["1"]
+          - field: query-string
+            name: tenant
+            values: ["beta"]
+          - field: source-ip
+            values: ["10.0.0.0/8"]
+    action:
+      type: forward
+      target-group: beta
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		stable := lb.NewForwardAction(loadBalancer.TargetGroups[0])
+		beta := lb.NewForwardAction(loadBalancer.TargetGroups[1])
+
+		headerRequest := httptest.NewRequest("GET", "http://localhost/", nil)
+		headerRequest.Header.Set("X-Beta", "1")
+		assert.Equal(t, beta, loadBalancer.Listeners[0].Rules.Match(headerRequest))
+
+		queryRequest := httptest.NewRequest("GET", "http://localhost/?tenant=beta", nil)
+		assert.Equal(t, beta, loadBalancer.Listeners[0].Rules.Match(queryRequest))
+
+		officeRequest := httptest.NewRequest("GET", "http://localhost/", nil)
+		officeRequest.RemoteAddr = "10.20.30.40:1234"
+		assert.Equal(t, beta, loadBalancer.Listeners[0].Rules.Match(officeRequest))
+
+		assert.Equal(t, stable, loadBalancer.Listeners[0].Rules.Match(httptest.NewRequest("GET", "http://localhost/", nil)))
+	})
+
+	t.Run("Should return an error when a header regex is invalid", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+port: 9000
+target-groups:
+  - name: test
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+rules:
+  - priority: 1
+    conditions:
+      - field: http-header-regex
+        name: User-Agent
+        values: ["("]
+    action:
+      type: forward
+      target-group: test
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.ErrorContains(t, err, `could not build listeners: could not build listener 9000: could not build rules: could not build rule 1: invalid header regex "("`)
+	})
+
+	t.Run("Should compile a weighted forward action with stickiness", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+port: 9000
+target-groups:
+  - name: node-server
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+  - name: node-server-canary
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+default-action:
+  type: forward
+  target-groups:
+    - name: node-server
+      weight: 95
+    - name: node-server-canary
+      weight: 5
+  stickiness:
+    enabled: true
+    duration: 600
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		action, ok := loadBalancer.Listeners[0].Rules.DefaultAction.(*lb.WeightedForwardAction)
+
+		assert.True(t, ok)
+		assert.Equal(t, []lb.WeightedTargetGroup{
+			{TargetGroup: loadBalancer.TargetGroups[0], Weight: 95},
+			{TargetGroup: loadBalancer.TargetGroups[1], Weight: 5},
+		}, action.TargetGroups)
+		assert.Equal(t, 10*time.Minute, action.StickinessDuration)
+	})
+
+	t.Run("Should compile fixed-response and redirect actions", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+port: 9000
+target-groups: []
+rules:
+  - priority: 1
+    conditions:
+      - field: path-prefix
+        values: ["/maintenance"]
+    action:
+      type: fixed-response
+      status-code: 503
+      content-type: text/plain
+      message-body: "down for maintenance"
+default-action:
+  type: redirect
+  protocol: HTTPS
+  port: "443"
+  status-code: 301
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		assert.Equal(t, lb.NewFixedResponseAction(503, "text/plain", "down for maintenance"), loadBalancer.Listeners[0].Rules.Rules[0].Action)
+		assert.Equal(t, lb.NewRedirectAction(lb.NewRedirectActionParams{
+			StatusCode: 301,
+			Protocol:   "HTTPS",
+			Port:       "443",
+		}), loadBalancer.Listeners[0].Rules.DefaultAction)
+	})
+
+	t.Run("Should return an error when a redirect would point to the same location", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+port: 9000
+target-groups: []
+default-action:
+  type: redirect
+  status-code: 302
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, "could not build listeners: could not build listener 9000: could not build rules: could not build default action: a redirect action must change at least one of protocol, host, port, path or query")
+	})
+
+	t.Run("Should build a plaintext listener from the port and the configured listeners", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+port: 9000
+target-groups: []
+listeners:
+  - port: 9001
+    protocol: http
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+		assert.Len(t, loadBalancer.Listeners, 2)
+
+		assert.Equal(t, 9000, loadBalancer.Listeners[0].Port)
+		assert.Equal(t, "http", loadBalancer.Listeners[0].Protocol)
+		assert.Equal(t, 9001, loadBalancer.Listeners[1].Port)
+		assert.Equal(t, "http", loadBalancer.Listeners[1].Protocol)
+	})
+
+	t.Run("Should build a rule set per listener", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: apps
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+  - name: internal
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+default-action:
+  type: forward
+  target-group: apps
+listeners:
+  - port: 80
+    default-action:
+      type: redirect
+      protocol: HTTPS
+      port: "443"
+      status-code: 301
+  - port: 8080
+  - port: 8443
+    default-action:
+      type: forward
+      target-group: internal
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+		assert.Len(t, loadBalancer.Listeners, 3)
+
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+
+		assert.IsType(t, &lb.RedirectAction{}, loadBalancer.Listeners[0].Rules.Match(r))
+		assert.Equal(t, lb.NewForwardAction(loadBalancer.TargetGroups[0]), loadBalancer.Listeners[1].Rules.Match(r))
+		assert.Equal(t, lb.NewForwardAction(loadBalancer.TargetGroups[1]), loadBalancer.Listeners[2].Rules.Match(r))
+	})
+
+	t.Run("Should return an error when two listeners share a port", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+port: 9000
+target-groups: []
+listeners:
+  - port: 9000
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, "could not build listeners: duplicate listener port 9000")
+	})
+
+	t.Run("Should return an error when two target groups share a name", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 8080
+  - name: app
+    targets:
+      - host: "localhost"
+        port: 8081
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, `duplicate target group name "app"`)
+	})
+
+	t.Run("Should return an error when an HTTPS listener certificate cannot be loaded", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups: []
+listeners:
+  - port: 443
+    protocol: https
+    tls:
+      certificates:
+        - cert-file: missing.pem
+          key-file: missing-key.pem
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.ErrorContains(t, err, "could not build listeners: could not build listener 443: could not stat certificate missing.pem")
+	})
+
+	t.Run("Should reach https target groups over TLS for requests and health checks", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+port: 9000
+target-groups:
+  - name: secure
+    protocol: https
+    tls:
+      server-name: backend.internal
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+      path: "/health"
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		healthCheckConfig := loadBalancer.TargetGroups[0].HealthCheckConfig
+
+		assert.Equal(t, "https", healthCheckConfig.Scheme)
+
+		transport, ok := healthCheckConfig.HttpClient.Transport.(*http.Transport)
+
+		assert.True(t, ok)
+		assert.Equal(t, "backend.internal", transport.TLSClientConfig.ServerName)
+
+		assert.NotEqual(t, algorithms.NewRoundRobin(loadBalancer.TargetGroups[0].Targets, testSetup.proxyFactory), loadBalancer.TargetGroups[0].Algorithm)
+	})
+
+	t.Run("Should return an error when the target CA bundle cannot be read", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: secure
+    protocol: https
+    tls:
+      ca-file: missing-ca.pem
+    algorithm:
+      type: round-robin
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.ErrorContains(t, err, "could not build target group secure: could not read CA bundle missing-ca.pem")
+	})
+
+	t.Run("Should build a TCP listener for a TCP target group", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: postgres
+    protocol: tcp
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 5432
+listeners:
+  - port: 5433
+    protocol: tcp
+    target-group: postgres
+    idle-timeout: 60
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		assert.Equal(t, "tcp", loadBalancer.TargetGroups[0].Protocol)
+		assert.Equal(t, "tcp", loadBalancer.TargetGroups[0].HealthCheckConfig.Type)
+
+		assert.Equal(t, "tcp", loadBalancer.Listeners[0].Protocol)
+		assert.Same(t, loadBalancer.TargetGroups[0], loadBalancer.Listeners[0].TargetGroup)
+	})
+
+	t.Run("Should build a UDP listener for a UDP target group with an explicit health check", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: dns
+    protocol: udp
+    algorithm:
+      type: round-robin
+    health-check:
+      type: tcp
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 53
+listeners:
+  - port: 5353
+    protocol: udp
+    target-group: dns
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		assert.Equal(t, "udp", loadBalancer.TargetGroups[0].Protocol)
+		assert.Equal(t, "tcp", loadBalancer.TargetGroups[0].HealthCheckConfig.Type)
+		assert.Equal(t, "udp", loadBalancer.Listeners[0].Protocol)
+	})
+
+	t.Run("Should return an error when a UDP target group has no health check type", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: dns
+    protocol: udp
+    algorithm:
+      type: round-robin
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, "could not build target group dns: udp target groups require a health check type, tcp or http")
+	})
+
+	t.Run("Should return an error when the algorithm of a TCP target group does not support connections", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: postgres
+    protocol: tcp
+    algorithm:
+      type: http-only
+    health-check:
+      interval: 1
+      timeout: 1
+listeners:
+  - port: 5433
+    protocol: tcp
+    target-group: postgres
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, "could not build listeners: could not build listener 5433: the algorithm of target group postgres does not support connections")
+	})
+
+	t.Run("Should return an error when the algorithm of a TCP target group does not support connections behind failover tiers", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: postgres
+    protocol: tcp
+    algorithm:
+      type: http-only
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "primary"
+        port: 5432
+      - host: "secondary"
+        port: 5432
+        priority: 1
+listeners:
+  - port: 5433
+    protocol: tcp
+    target-group: postgres
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, "could not build listeners: could not build listener 5433: the algorithm of target group postgres does not support connections")
+	})
+
+	t.Run("Should return an error when an HTTP rule forwards to a TCP target group", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+port: 9000
+target-groups:
+  - name: postgres
+    protocol: tcp
+    algorithm:
+      type: round-robin
+default-action:
+  type: forward
+  target-group: postgres
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.ErrorContains(t, err, "target group postgres uses protocol tcp and cannot receive HTTP requests")
+	})
+
+	t.Run("Should build an h2c transport for target groups using protocol-version h2c", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: grpc
+    protocol-version: h2c
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+      timeout: 1
+listeners:
+  - port: 9001
+    h2c: true
+    default-action:
+      type: forward
+      target-group: grpc
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		transport, ok := loadBalancer.TargetGroups[0].HealthCheckConfig.HttpClient.Transport.(*http.Transport)
+
+		assert.True(t, ok)
+		assert.True(t, transport.Protocols.UnencryptedHTTP2())
+		assert.False(t, transport.Protocols.HTTP1())
+
+		assert.True(t, loadBalancer.Listeners[0].H2C)
+		testSetup.proxyFactory.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("Should return an error when the protocol-version does not fit the protocol", func(t *testing.T) {
+		cases := map[string]string{
+			"protocol: http\n    protocol-version: http2": "could not build target group app: protocol-version http2 requires protocol https, use h2c for cleartext HTTP/2",
+			"protocol: https\n    protocol-version: h2c":  "could not build target group app: protocol-version h2c requires protocol http, use http2 over TLS",
+			"protocol: tcp\n    protocol-version: http2":  "could not build target group app: protocol-version is not supported with protocol tcp",
+			"protocol: http\n    protocol-version: http3": "could not build target group app: unknown protocol-version \"http3\"",
+		}
+
+		for protocol, expectedErr := range cases {
+			testSetup := setup()
+
+			testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    `+protocol+`
+    algorithm:
+      type: round-robin
+`), nil)
+
+			_, err := testSetup.configLoader.Load()
+
+			assert.EqualError(t, err, expectedErr)
+		}
+	})
+
+	t.Run("Should return an error when h2c is enabled on an HTTPS listener", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+listeners:
+  - port: 443
+    protocol: https
+    h2c: true
+    default-action:
+      type: fixed-response
+      status-code: 200
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.ErrorContains(t, err, "h2c is only supported with protocol http")
+	})
+
+	t.Run("Should build gRPC health checks and gRPC method conditions", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: orders
+    protocol-version: h2c
+    algorithm:
+      type: round-robin
+    health-check:
+      type: grpc
+      grpc-service: orders.v1.Orders
+      interval: 1
+      timeout: 1
+listeners:
+  - port: 9001
+    h2c: true
+    rules:
+      - priority: 1
+        conditions:
+          - field: grpc-method
+            values: ["orders.v1.Orders/*"]
+        action:
+          type: forward
+          target-group: orders
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		assert.Equal(t, "grpc", loadBalancer.TargetGroups[0].HealthCheckConfig.Type)
+		assert.Equal(t, "orders.v1.Orders", loadBalancer.TargetGroups[0].HealthCheckConfig.GrpcService)
+
+		r := httptest.NewRequest("POST", "http://localhost/orders.v1.Orders/Create", nil)
+		r.Header.Set("Content-Type", "application/grpc")
+
+		assert.Equal(t, loadBalancer.Listeners[0].Rules.Rules[0].Action, loadBalancer.Listeners[0].Rules.Match(r))
+	})
+
+	t.Run("Should return an error when a gRPC health check cannot use HTTP/2", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: orders
+    algorithm:
+      type: round-robin
+    health-check:
+      type: grpc
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, "could not build target group orders: health check type grpc requires protocol-version h2c or protocol https")
+	})
+
+	t.Run("Should cap the upgraded connections of every target of a target group", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: realtime
+    max-upgraded-connections: 500
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 8080
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(500), loadBalancer.TargetGroups[0].Targets[0].MaxUpgradedConns)
+	})
+
+	t.Run("Should build a least-connections algorithm", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: least-connections
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 8080
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+		assert.Equal(t, algorithms.NewLeastConnections(loadBalancer.TargetGroups[0].Targets, testSetup.proxyFactory), loadBalancer.TargetGroups[0].Algorithm)
+	})
+
+	t.Run("Should build a consistent-hash algorithm from its options", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: consistent-hash
+      options:
+        hash-key: cookie
+        hash-key-name: session
+        virtual-nodes: 100
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 8080
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+		assert.Equal(t, algorithms.NewConsistentHash(loadBalancer.TargetGroups[0].Targets, testSetup.proxyFactory, algorithms.NewConsistentHashOptions{
+			Key:          algorithms.HashKey{Source: algorithms.HashKeyCookie, Name: "session"},
+			VirtualNodes: 100,
+		}), loadBalancer.TargetGroups[0].Algorithm)
+	})
+
+	t.Run("Should return an error when the consistent-hash options are invalid", func(t *testing.T) {
+		cases := map[string]string{
+			"hash-key: header":  "could not build target group app: invalid options for algorithm consistent-hash: hash-key header requires a hash-key-name",
+			"hash-key: body":    "could not build target group app: invalid options for algorithm consistent-hash: unknown hash-key \"body\"",
+			"virtual-nodes: -1": "could not build target group app: invalid options for algorithm consistent-hash: virtual-nodes must be a positive integer",
+			"hash-key: [ip]":    "could not build target group app: invalid options for algorithm consistent-hash: hash-key must be a string",
+			"hash-key: header\n        hash-key-name: 42": "could not build target group app: invalid options for algorithm consistent-hash: hash-key-name must be a string",
+		}
+
+		for option, expectedErr := range cases {
+			testSetup := setup()
+
+			testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: consistent-hash
+      options:
+        `+option+`
+`), nil)
+
+			_, err := testSetup.configLoader.Load()
+
+			assert.EqualError(t, err, expectedErr)
+		}
+	})
+
+	t.Run("Should build a maglev algorithm with a prime table size", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: cache
+    algorithm:
+      type: maglev
+      options:
+        table-size: 251
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 11211
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+		assert.Equal(t, algorithms.NewMaglev(loadBalancer.TargetGroups[0].Targets, testSetup.proxyFactory, algorithms.NewMaglevOptions{
+			Key:       algorithms.HashKey{Source: algorithms.HashKeyIp},
+			TableSize: 251,
+		}), loadBalancer.TargetGroups[0].Algorithm)
+	})
+
+	t.Run("Should return an error when the maglev table size is not a prime", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: cache
+    algorithm:
+      type: maglev
+      options:
+        table-size: 1000
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, "could not build target group cache: invalid options for algorithm maglev: table-size must be a prime number")
+	})
+
+	t.Run("Should wrap the algorithm of a target group with a sticky cookie", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: least-connections
+    stickiness:
+      type: lb-cookie
+      duration: 600
+      secret: s3cr3t
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 8080
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		target := loadBalancer.TargetGroups[0].Targets[0]
+		target.SetHealthy(true)
+
+		proxy := &ProxyMock{}
+		testSetup.proxyFactory.On("Create", "localhost", 8080).Return(proxy)
+		proxy.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+		w := httptest.NewRecorder()
+
+		assert.Nil(t, loadBalancer.TargetGroups[0].Algorithm.Handle(w, httptest.NewRequest("GET", "http://localhost/", nil)))
+		assert.Equal(t, algorithms.StickyCookieName, w.Result().Cookies()[0].Name)
+		assert.Equal(t, 600, w.Result().Cookies()[0].MaxAge)
+	})
+
+	t.Run("Should return an error when the stickiness is not supported", func(t *testing.T) {
+		cases := map[string]string{
+			"protocol: http\n    stickiness:\n      type: source-ip":  "could not build target group app: unknown stickiness type \"source-ip\"",
+			"protocol: tcp\n    stickiness:\n      type: lb-cookie":   "could not build target group app: stickiness is not supported with protocol tcp",
+			"protocol: http\n    stickiness:\n      type: app-cookie": "could not build target group app: stickiness type app-cookie requires a cookie-name",
+		}
+
+		for option, expectedErr := range cases {
+			testSetup := setup()
+
+			testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    `+option+`
+    algorithm:
+      type: round-robin
+`), nil)
+
+			_, err := testSetup.configLoader.Load()
+
+			assert.EqualError(t, err, expectedErr)
+		}
+	})
+
+	t.Run("Should wrap the algorithm of a target group with application cookie stickiness", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: legacy
+    algorithm:
+      type: round-robin
+    stickiness:
+      type: app-cookie
+      cookie-name: JSESSIONID
+      max-entries: 100
+      duration: 1800
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 8080
+      - host: "localhost"
+        port: 8081
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		for _, target := range loadBalancer.TargetGroups[0].Targets {
+			target.SetHealthy(true)
+		}
+
+		proxy := &ProxyMock{}
+		testSetup.proxyFactory.On("Create", "localhost", 8080).Return(proxy)
+		proxy.On("ServeHTTP", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			http.SetCookie(args.Get(0).(http.ResponseWriter), &http.Cookie{Name: "JSESSIONID", Value: "abc"})
+		}).Return()
+
+		algorithm := loadBalancer.TargetGroups[0].Algorithm
+
+		assert.Nil(t, algorithm.Handle(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil)))
+
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+		r.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: "abc"})
+
+		assert.Nil(t, algorithm.Handle(httptest.NewRecorder(), r))
+
+		testSetup.proxyFactory.AssertNumberOfCalls(t, "Create", 2)
+		testSetup.proxyFactory.AssertNotCalled(t, "Create", "localhost", 8081)
+	})
+
+	t.Run("Should build a weighted round robin over weighted targets", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: weighted-round-robin
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 8080
+        weight: 3
+      - host: "localhost"
+        port: 8081
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		targets := loadBalancer.TargetGroups[0].Targets
+
+		assert.Equal(t, 3, targets[0].Weight)
+		assert.Equal(t, 1, targets[1].Weight)
+		assert.Equal(t, algorithms.NewWeightedRoundRobin(targets, testSetup.proxyFactory), loadBalancer.TargetGroups[0].Algorithm)
+	})
+
+	t.Run("Should return an error when a target has a negative weight", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: weighted-round-robin
+    targets:
+      - host: "localhost"
+        port: 8080
+        weight: -1
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, "could not build target group app: target localhost:8080 has a negative weight")
+	})
+
+	t.Run("Should build random and p2c algorithms", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: random
+    algorithm:
+      type: random
+    health-check:
+      interval: 1
+      timeout: 1
+  - name: p2c
+    algorithm:
+      type: p2c
+      options:
+        compare: ewma
+        decay: 5
+    health-check:
+      interval: 1
+      timeout: 1
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		assert.IsType(t, algorithms.NewRandom(nil, nil), loadBalancer.TargetGroups[0].Algorithm)
+		assert.IsType(t, algorithms.NewP2C(nil, nil, algorithms.NewP2COptions{}), loadBalancer.TargetGroups[1].Algorithm)
+	})
+
+	t.Run("Should return an error when the p2c options are invalid", func(t *testing.T) {
+		cases := map[string]string{
+			"compare: latency": "could not build target group app: invalid options for algorithm p2c: unknown p2c compare \"latency\"",
+			"decay: -1":        "could not build target group app: invalid options for algorithm p2c: decay must be a positive number of seconds",
+			"compare: 1":       "could not build target group app: invalid options for algorithm p2c: compare must be a string",
+		}
+
+		for option, expectedErr := range cases {
+			testSetup := setup()
+
+			testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: p2c
+      options:
+        `+option+`
+`), nil)
+
+			_, err := testSetup.configLoader.Load()
+
+			assert.EqualError(t, err, expectedErr)
+		}
+	})
+
+	t.Run("Should build an ewma algorithm with its decay", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: ewma
+      options:
+        decay: 2.5
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 8080
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+		assert.Equal(t, algorithms.NewPeakEwma(loadBalancer.TargetGroups[0].Targets, testSetup.proxyFactory, algorithms.NewPeakEwmaOptions{
+			Decay: 2500 * time.Millisecond,
+		}), loadBalancer.TargetGroups[0].Algorithm)
+	})
+	t.Run("Should fail over to the secondary tier of a target group", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: round-robin
+    failover:
+      healthy-percent: 50
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "primary"
+        port: 8080
+      - host: "secondary"
+        port: 8080
+        priority: 1
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		targets := loadBalancer.TargetGroups[0].Targets
+		assert.Equal(t, 1, targets[1].Priority)
+
+		targets[0].SetHealthy(false)
+		targets[1].SetHealthy(true)
+
+		proxy := &ProxyMock{}
+		testSetup.proxyFactory.On("Create", "secondary", 8080).Return(proxy)
+		proxy.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+		algorithm := loadBalancer.TargetGroups[0].Algorithm
+
+		assert.Nil(t, algorithm.Handle(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil)))
+
+		testSetup.proxyFactory.AssertNotCalled(t, "Create", "primary", 8080)
+	})
+
+	t.Run("Should use the default healthy percent of 70 when it is 0", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: round-robin
+    failover:
+      healthy-percent: 0
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "primary"
+        port: 8080
+      - host: "primary"
+        port: 8081
+      - host: "primary"
+        port: 8082
+      - host: "secondary"
+        port: 8080
+        priority: 1
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		// 2 of 3 primary targets are healthy, below 70%, so some traffic
+		// spills over to the secondary tier.
+		targets := loadBalancer.TargetGroups[0].Targets
+
+		for _, target := range targets[1:] {
+			target.SetHealthy(true)
+		}
+
+		proxy := &ProxyMock{}
+		testSetup.proxyFactory.On("Create", mock.Anything, mock.Anything).Return(proxy)
+		proxy.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+		algorithm := loadBalancer.TargetGroups[0].Algorithm
+
+		for i := 0; i < 1000; i++ {
+			assert.Nil(t, algorithm.Handle(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil)))
+		}
+
+		testSetup.proxyFactory.AssertCalled(t, "Create", "secondary", 8080)
+	})
+
+	t.Run("Should return an error when the failover options are invalid", func(t *testing.T) {
+		cases := map[string]string{
+			"failover:\n      healthy-percent: 150":                                       "could not build target group app: failover healthy-percent must be between 1 and 100, or 0 for the default of 70",
+			"targets:\n      - host: localhost\n        port: 8080\n        priority: -1": "could not build target group app: target localhost:8080 has a negative priority",
+		}
+
+		for option, expectedErr := range cases {
+			testSetup := setup()
+
+			testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: round-robin
+    `+option+`
+`), nil)
+
+			_, err := testSetup.configLoader.Load()
+
+			assert.EqualError(t, err, expectedErr)
+		}
+	})
+	t.Run("Should prefer the targets in the zone of the load balancer", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+zone: eu-west-1a
+target-groups:
+  - name: app
+    algorithm:
+      type: round-robin
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "local"
+        port: 8080
+        zone: eu-west-1a
+      - host: "remote"
+        port: 8080
+        zone: eu-west-1b
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		targets := loadBalancer.TargetGroups[0].Targets
+		assert.Equal(t, "eu-west-1b", targets[1].Zone)
+
+		for _, target := range targets {
+			target.SetHealthy(true)
+		}
+
+		proxy := &ProxyMock{}
+		testSetup.proxyFactory.On("Create", "local", 8080).Return(proxy)
+		proxy.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+		algorithm := loadBalancer.TargetGroups[0].Algorithm
+
+		for i := 0; i < 10; i++ {
+			assert.Nil(t, algorithm.Handle(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil)))
+		}
+
+		testSetup.proxyFactory.AssertNotCalled(t, "Create", "remote", 8080)
+	})
+	t.Run("Should return an error when a hashing algorithm is split into tiers or zones", func(t *testing.T) {
+		cases := map[string]string{
+			"priority: 1":      "could not build target group app: failover tiers are not supported with algorithm consistent-hash",
+			"zone: eu-west-1b": "could not build target group app: zones are not supported with algorithm consistent-hash",
+		}
+
+		for option, expectedErr := range cases {
+			testSetup := setup()
+
+			testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+zone: eu-west-1a
+target-groups:
+  - name: app
+    algorithm:
+      type: consistent-hash
+    targets:
+      - host: "local"
+        port: 8080
+        zone: eu-west-1a
+      - host: "remote"
+        port: 8080
+        `+option+`
+`), nil)
+
+			_, err := testSetup.configLoader.Load()
+
+			assert.EqualError(t, err, expectedErr)
+		}
+	})
+
+	t.Run("Should return an error when an external hashing algorithm is split into tiers", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: external-hash
+    targets:
+      - host: "primary"
+        port: 8080
+      - host: "secondary"
+        port: 8080
+        priority: 1
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, "could not build target group app: failover tiers are not supported with algorithm external-hash")
+	})
+
+	t.Run("Should return an error when an external hashing algorithm is split into zones", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+zone: eu-west-1a
+target-groups:
+  - name: app
+    algorithm:
+      type: external-hash
+    targets:
+      - host: "local"
+        port: 8080
+        zone: eu-west-1a
+      - host: "remote"
+        port: 8080
+        zone: eu-west-1b
+`), nil)
+
+		_, err := testSetup.configLoader.Load()
+
+		assert.EqualError(t, err, "could not build target group app: zones are not supported with algorithm external-hash")
+	})
+	t.Run("Should ramp up the targets of a target group with slow start", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      type: weighted-round-robin
+    slow-start:
+      duration: 30
+      aggression: 1.5
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 8080
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+
+		target := loadBalancer.TargetGroups[0].Targets[0]
+
+		assert.Equal(t, 30*time.Second, target.SlowStart)
+		assert.Equal(t, 1.5, target.SlowStartAggression)
+	})
+
+	t.Run("Should return an error when the slow start is invalid", func(t *testing.T) {
+		for _, option := range []string{"duration: 0", "duration: 10\n      aggression: -1"} {
+			testSetup := setup()
+
+			testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    slow-start:
+      `+option+`
+`), nil)
+
+			_, err := testSetup.configLoader.Load()
+
+			assert.EqualError(t, err, "could not build target group app: slow-start requires a positive duration and a non-negative aggression")
+		}
+	})
+	t.Run("Should return an error when the algorithm is unknown or its options are invalid", func(t *testing.T) {
+		cases := map[string]string{
+			"type: fastest": "could not build target group app: unknown algorithm \"fastest\", expected one of [consistent-hash ewma external-hash http-only least-connections least-response-time maglev p2c random round-robin weighted-round-robin]",
+			"type: least-response-time\n      options:\n        max-consecutive-requests: many": "could not build target group app: invalid options for algorithm least-response-time: max-consecutive-requests must be a positive integer",
+			"type: round-robin\n      options:\n        decay: 10":                              "could not build target group app: invalid options for algorithm round-robin: unknown option \"decay\"",
+			"type: maglev\n      options:\n        virtual-nodes: 10":                           "could not build target group app: invalid options for algorithm maglev: unknown option \"virtual-nodes\"",
+		}
+
+		for algorithm, expectedErr := range cases {
+			testSetup := setup()
+
+			testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    algorithm:
+      `+algorithm+`
+`), nil)
+
+			_, err := testSetup.configLoader.Load()
+
+			assert.EqualError(t, err, expectedErr)
+		}
+	})
+
+	t.Run("Should build a least-response-time algorithm without max-consecutive-requests", func(t *testing.T) {
+		testSetup := setup()
+
+		testSetup.fileReader.On("Read", "config.yaml").Return([]byte(`
+target-groups:
+  - name: app
+    health-check:
+      interval: 1
+      timeout: 1
+    targets:
+      - host: "localhost"
+        port: 8080
+`), nil)
+
+		loadBalancer, err := testSetup.configLoader.Load()
+
+		assert.Nil(t, err)
+		assert.Equal(t, algorithms.NewLeastResponseTime(loadBalancer.TargetGroups[0].Targets, testSetup.proxyFactory, algorithms.NewLeastResponseTimeOptions{
+			MaxConsecutiveRequests: math.MaxInt64,
+		}), loadBalancer.TargetGroups[0].Algorithm)
+	})
 }
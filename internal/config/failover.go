@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/joaosczip/go-lb/internal/algorithms"
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	"github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+type Failover struct {
+	HealthyPercent int `yaml:"healthy-percent,omitempty"`
+}
+
+// getTieredAlgorithm builds one algorithm per priority tier when the targets
+// of the target group declare more than one, and a single algorithm
+// otherwise.
+func (c *ConfigLoader) getTieredAlgorithm(tg TargetGroup, localZone string, targets []*targetgroup.Target, proxyFactory proxy.ProxyFactory) (alg.Algorithm, error) {
+	tiered := false
+
+	for _, target := range targets {
+		if target.Priority != targets[0].Priority {
+			tiered = true
+		}
+	}
+
+	if tg.Failover != nil && (tg.Failover.HealthyPercent < 0 || tg.Failover.HealthyPercent > 100) {
+		return nil, fmt.Errorf("failover healthy-percent must be between 1 and 100, or 0 for the default of %d", algorithms.DefaultFailoverHealthyPercent)
+	}
+
+	if !tiered {
+		return c.getZonedAlgorithm(tg, localZone, targets, proxyFactory)
+	}
+
+	if alg.PinsKeys(tg.Algorithm.Type) {
+		return nil, fmt.Errorf("failover tiers are not supported with algorithm %s", tg.Algorithm.Type)
+	}
+
+	var opts algorithms.NewPriorityTiersOptions
+
+	if tg.Failover != nil {
+		opts.HealthyPercent = tg.Failover.HealthyPercent
+	}
+
+	tiers, err := algorithms.NewPriorityTiers(targets, func(tierTargets []*targetgroup.Target) (alg.Algorithm, error) {
+		return c.getZonedAlgorithm(tg, localZone, tierTargets, proxyFactory)
+	}, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tiers, nil
+}
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/certificates"
+	"github.com/joaosczip/go-lb/internal/proxy"
+	"github.com/joaosczip/go-lb/pkg/lb"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	"github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+type Listener struct {
+	Port          int     `yaml:"port"`
+	Protocol      string  `yaml:"protocol"`
+	TLS           *TLS    `yaml:"tls,omitempty"`
+	Rules         []Rule  `yaml:"rules,omitempty"`
+	DefaultAction *Action `yaml:"default-action,omitempty"`
+	TargetGroup   string  `yaml:"target-group,omitempty"`
+	IdleTimeout   int     `yaml:"idle-timeout,omitempty"`
+	ProxyProtocol bool    `yaml:"proxy-protocol,omitempty"`
+	H2C           bool    `yaml:"h2c,omitempty"`
+}
+
+type TLS struct {
+	Certificates   []Certificate `yaml:"certificates"`
+	MinVersion     string        `yaml:"min-version,omitempty"`
+	CipherSuites   []string      `yaml:"cipher-suites,omitempty"`
+	ReloadInterval int           `yaml:"reload-interval,omitempty"`
+	ClientAuth     *ClientAuth   `yaml:"client-auth,omitempty"`
+}
+
+type ClientAuth struct {
+	CAFile string `yaml:"ca-file"`
+	Mode   string `yaml:"mode,omitempty"`
+}
+
+type Certificate struct {
+	CertFile string `yaml:"cert-file"`
+	KeyFile  string `yaml:"key-file"`
+}
+
+const defaultCertificateReloadInterval = 10
+
+func buildTLSConfig(tlsConfig *TLS) (*tls.Config, error) {
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("missing tls configuration")
+	}
+
+	minVersion, err := certificates.ParseTLSVersion(tlsConfig.MinVersion)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := certificates.ParseCipherSuites(tlsConfig.CipherSuites)
+
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]certificates.KeyPairFiles, len(tlsConfig.Certificates))
+
+	for i, certificate := range tlsConfig.Certificates {
+		files[i] = certificates.KeyPairFiles{
+			CertFile: certificate.CertFile,
+			KeyFile:  certificate.KeyFile,
+		}
+	}
+
+	reloadInterval := tlsConfig.ReloadInterval
+
+	if reloadInterval == 0 {
+		reloadInterval = defaultCertificateReloadInterval
+	}
+
+	store, err := certificates.NewStore(files, time.Duration(reloadInterval)*time.Second)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		GetCertificate: store.GetCertificate,
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+	}
+
+	if tlsConfig.ClientAuth != nil {
+		if err := configureClientAuth(config, tlsConfig.ClientAuth); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+func configureClientAuth(config *tls.Config, clientAuth *ClientAuth) error {
+	switch clientAuth.Mode {
+	case "", "require":
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return fmt.Errorf("unknown client-auth mode %q", clientAuth.Mode)
+	}
+
+	pool, err := certificates.LoadCertPool(clientAuth.CAFile)
+
+	if err != nil {
+		return err
+	}
+
+	config.ClientCAs = pool
+
+	return nil
+}
+
+const (
+	defaultTcpIdleTimeout = 350
+	defaultUdpIdleTimeout = 120
+)
+
+func getProxyProtocolVersion(targetGroupName string, targetGroupsConfig []TargetGroup) (int, error) {
+	for _, tg := range targetGroupsConfig {
+		if tg.Name != targetGroupName {
+			continue
+		}
+
+		switch tg.ProxyProtocol {
+		case "":
+			return 0, nil
+		case "v1":
+			return 1, nil
+		case "v2":
+			return 2, nil
+		}
+
+		return 0, fmt.Errorf("unknown proxy-protocol version %q for target group %s", tg.ProxyProtocol, tg.Name)
+	}
+
+	return 0, nil
+}
+
+func buildConnListener(listener Listener, targetGroups []*targetgroup.TargetGroup, targetGroupsConfig []TargetGroup) (*lb.Listener, error) {
+	if len(listener.Rules) > 0 || listener.DefaultAction != nil {
+		return nil, fmt.Errorf("%s listeners do not support rules, use target-group instead", listener.Protocol)
+	}
+
+	var targetGroup *targetgroup.TargetGroup
+
+	for _, tg := range targetGroups {
+		if tg.Name == listener.TargetGroup {
+			targetGroup = tg
+		}
+	}
+
+	if targetGroup == nil {
+		return nil, fmt.Errorf("unknown target group %q", listener.TargetGroup)
+	}
+
+	if targetGroup.Protocol != listener.Protocol {
+		return nil, fmt.Errorf("target group %s uses protocol %s, expected %s", targetGroup.Name, targetGroup.Protocol, listener.Protocol)
+	}
+
+	if !alg.SupportsConns(targetGroup.Algorithm) {
+		return nil, fmt.Errorf("the algorithm of target group %s does not support connections", targetGroup.Name)
+	}
+
+	params := lb.NewListenerParams{
+		Port:          listener.Port,
+		Protocol:      listener.Protocol,
+		TargetGroup:   targetGroup,
+		ProxyProtocol: listener.ProxyProtocol,
+	}
+
+	proxyProtocolVersion, err := getProxyProtocolVersion(targetGroup.Name, targetGroupsConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	idleTimeout := time.Duration(listener.IdleTimeout) * time.Second
+
+	if listener.Protocol == "udp" {
+		if listener.ProxyProtocol || proxyProtocolVersion != 0 {
+			return nil, fmt.Errorf("proxy-protocol is not supported with protocol udp")
+		}
+
+		if idleTimeout == 0 {
+			idleTimeout = defaultUdpIdleTimeout * time.Second
+		}
+
+		params.ConnProxy = proxy.NewUDPProxy(idleTimeout)
+	} else {
+		if idleTimeout == 0 {
+			idleTimeout = defaultTcpIdleTimeout * time.Second
+		}
+
+		params.ConnProxy = proxy.NewTCPProxy(proxy.NewTCPProxyParams{
+			IdleTimeout:          idleTimeout,
+			ProxyProtocolVersion: proxyProtocolVersion,
+		})
+	}
+
+	return lb.NewListener(params), nil
+}
+
+func buildListener(listener Listener, targetGroups []*targetgroup.TargetGroup, targetGroupsConfig []TargetGroup) (*lb.Listener, error) {
+	if listener.Protocol == "tcp" || listener.Protocol == "udp" {
+		if listener.H2C {
+			return nil, fmt.Errorf("h2c is only supported with protocol http")
+		}
+
+		return buildConnListener(listener, targetGroups, targetGroupsConfig)
+	}
+
+	rules, err := buildRuleTable(listener.Rules, listener.DefaultAction, targetGroups)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not build rules: %v", err)
+	}
+
+	params := lb.NewListenerParams{
+		Port:          listener.Port,
+		Protocol:      listener.Protocol,
+		Rules:         rules,
+		ProxyProtocol: listener.ProxyProtocol,
+		H2C:           listener.H2C,
+	}
+
+	switch listener.Protocol {
+	case "", "http":
+		params.Protocol = "http"
+	case "https":
+		if listener.H2C {
+			return nil, fmt.Errorf("h2c is only supported with protocol http, https negotiates HTTP/2 through ALPN")
+		}
+
+		tlsConfig, err := buildTLSConfig(listener.TLS)
+
+		if err != nil {
+			return nil, err
+		}
+
+		params.TLSConfig = tlsConfig
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", listener.Protocol)
+	}
+
+	return lb.NewListener(params), nil
+}
+
+// buildListeners builds the plaintext listener on the top-level port, if any,
+// followed by the configured listeners. The top-level rules apply to the port
+// listener and to every listener that declares neither rules nor a default
+// action of its own.
+func buildListeners(config LBConfig, targetGroups []*targetgroup.TargetGroup) ([]*lb.Listener, error) {
+	var listenersConfig []Listener
+
+	if config.Port != 0 {
+		listenersConfig = append(listenersConfig, Listener{Port: config.Port, Protocol: "http"})
+	}
+
+	listenersConfig = append(listenersConfig, config.Listeners...)
+
+	listeners := make([]*lb.Listener, 0, len(listenersConfig))
+	ports := make(map[string]bool, len(listenersConfig))
+
+	for _, listenerConfig := range listenersConfig {
+		// UDP listeners may share a port number with a TCP based one, e.g. DNS on 53.
+		socket := fmt.Sprintf("tcp/%d", listenerConfig.Port)
+
+		if listenerConfig.Protocol == "udp" {
+			socket = fmt.Sprintf("udp/%d", listenerConfig.Port)
+		}
+
+		if ports[socket] {
+			return nil, fmt.Errorf("duplicate listener port %d", listenerConfig.Port)
+		}
+
+		ports[socket] = true
+
+		isConnListener := listenerConfig.Protocol == "tcp" || listenerConfig.Protocol == "udp"
+
+		if !isConnListener && listenerConfig.Rules == nil && listenerConfig.DefaultAction == nil {
+			listenerConfig.Rules = config.Rules
+			listenerConfig.DefaultAction = config.DefaultAction
+		}
+
+		listener, err := buildListener(listenerConfig, targetGroups, config.TargetGroups)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not build listener %d: %v", listenerConfig.Port, err)
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
@@ -0,0 +1,342 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/joaosczip/go-lb/pkg/lb"
+	"github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+type Rule struct {
+	Priority   int         `yaml:"priority"`
+	Conditions []Condition `yaml:"conditions"`
+	Action     Action      `yaml:"action"`
+}
+
+type Condition struct {
+	Field  string      `yaml:"field,omitempty"`
+	Name   string      `yaml:"name,omitempty"`
+	Values []string    `yaml:"values,omitempty"`
+	Any    []Condition `yaml:"any,omitempty"`
+	All    []Condition `yaml:"all,omitempty"`
+}
+
+type Action struct {
+	Type         string                `yaml:"type"`
+	TargetGroup  string                `yaml:"target-group,omitempty"`
+	TargetGroups []WeightedTargetGroup `yaml:"target-groups,omitempty"`
+	Stickiness   *Stickiness           `yaml:"stickiness,omitempty"`
+	StatusCode   int                   `yaml:"status-code,omitempty"`
+	ContentType  string                `yaml:"content-type,omitempty"`
+	MessageBody  string                `yaml:"message-body,omitempty"`
+	Protocol     string                `yaml:"protocol,omitempty"`
+	Host         string                `yaml:"host,omitempty"`
+	Port         string                `yaml:"port,omitempty"`
+	Path         string                `yaml:"path,omitempty"`
+	Query        string                `yaml:"query,omitempty"`
+}
+
+type WeightedTargetGroup struct {
+	Name   string `yaml:"name"`
+	Weight int    `yaml:"weight"`
+}
+
+type Stickiness struct {
+	Enabled  bool `yaml:"enabled"`
+	Duration int  `yaml:"duration"`
+}
+
+func buildConditions(conditions []Condition) ([]lb.Condition, error) {
+	compiled := make([]lb.Condition, 0, len(conditions))
+
+	for _, condition := range conditions {
+		c, err := buildCondition(condition)
+
+		if err != nil {
+			return nil, err
+		}
+
+		compiled = append(compiled, c)
+	}
+
+	return compiled, nil
+}
+
+func buildCondition(condition Condition) (lb.Condition, error) {
+	if len(condition.Any) > 0 || len(condition.All) > 0 {
+		return buildCompoundCondition(condition)
+	}
+
+	if condition.Field == "query-string" {
+		if condition.Name == "" {
+			return nil, fmt.Errorf("condition %q has no name", condition.Field)
+		}
+
+		return lb.NewQueryStringCondition(condition.Name, condition.Values), nil
+	}
+
+	if len(condition.Values) == 0 {
+		return nil, fmt.Errorf("condition %q has no values", condition.Field)
+	}
+
+	switch condition.Field {
+	case "path-pattern":
+		return lb.NewPathPatternCondition(condition.Values), nil
+	case "path-prefix":
+		return lb.NewPathPrefixCondition(condition.Values), nil
+	case "host-header":
+		return lb.NewHostHeaderCondition(condition.Values), nil
+	case "http-request-method":
+		return lb.NewHttpMethodCondition(condition.Values), nil
+	case "grpc-method":
+		return lb.NewGrpcMethodCondition(condition.Values), nil
+	case "http-header", "http-header-regex":
+		return buildHttpHeaderCondition(condition)
+	case "source-ip":
+		return buildSourceIpCondition(condition)
+	}
+
+	return nil, fmt.Errorf("unknown condition field %q", condition.Field)
+}
+
+func buildCompoundCondition(condition Condition) (lb.Condition, error) {
+	if condition.Field != "" || (len(condition.Any) > 0 && len(condition.All) > 0) {
+		return nil, fmt.Errorf("a condition must declare exactly one of field, any or all")
+	}
+
+	if len(condition.Any) > 0 {
+		conditions, err := buildConditions(condition.Any)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return lb.NewAnyCondition(conditions), nil
+	}
+
+	conditions, err := buildConditions(condition.All)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lb.NewAllCondition(conditions), nil
+}
+
+func buildHttpHeaderCondition(condition Condition) (lb.Condition, error) {
+	if condition.Name == "" {
+		return nil, fmt.Errorf("condition %q has no name", condition.Field)
+	}
+
+	if condition.Field == "http-header" {
+		return lb.NewHttpHeaderCondition(condition.Name, condition.Values), nil
+	}
+
+	patterns := make([]*regexp.Regexp, len(condition.Values))
+
+	for i, value := range condition.Values {
+		pattern, err := regexp.Compile(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid header regex %q: %v", value, err)
+		}
+
+		patterns[i] = pattern
+	}
+
+	return lb.NewHttpHeaderRegexCondition(condition.Name, patterns), nil
+}
+
+func buildSourceIpCondition(condition Condition) (lb.Condition, error) {
+	prefixes := make([]netip.Prefix, len(condition.Values))
+
+	for i, value := range condition.Values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid source ip %q: %v", value, err)
+			}
+
+			prefixes[i] = netip.PrefixFrom(addr, addr.BitLen())
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid source ip %q: %v", value, err)
+		}
+
+		prefixes[i] = prefix.Masked()
+	}
+
+	return lb.NewSourceIpCondition(prefixes), nil
+}
+
+func findHttpTargetGroup(name string, targetGroups map[string]*targetgroup.TargetGroup) (*targetgroup.TargetGroup, error) {
+	targetGroup, ok := targetGroups[name]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown target group %q", name)
+	}
+
+	if targetGroup.Protocol != "http" && targetGroup.Protocol != "https" {
+		return nil, fmt.Errorf("target group %s uses protocol %s and cannot receive HTTP requests", name, targetGroup.Protocol)
+	}
+
+	return targetGroup, nil
+}
+
+func buildAction(action Action, targetGroups map[string]*targetgroup.TargetGroup) (lb.Action, error) {
+	switch action.Type {
+	case "forward":
+		if len(action.TargetGroups) > 0 {
+			return buildWeightedForwardAction(action, targetGroups)
+		}
+
+		targetGroup, err := findHttpTargetGroup(action.TargetGroup, targetGroups)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return lb.NewForwardAction(targetGroup), nil
+	case "fixed-response":
+		return buildFixedResponseAction(action)
+	case "redirect":
+		return buildRedirectAction(action)
+	}
+
+	return nil, fmt.Errorf("unknown action type %q", action.Type)
+}
+
+func buildFixedResponseAction(action Action) (lb.Action, error) {
+	if action.StatusCode < 200 || action.StatusCode > 599 {
+		return nil, fmt.Errorf("invalid fixed-response status code %d", action.StatusCode)
+	}
+
+	return lb.NewFixedResponseAction(action.StatusCode, action.ContentType, action.MessageBody), nil
+}
+
+func buildRedirectAction(action Action) (lb.Action, error) {
+	switch action.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil, fmt.Errorf("invalid redirect status code %d", action.StatusCode)
+	}
+
+	redirect := lb.NewRedirectAction(lb.NewRedirectActionParams{
+		StatusCode: action.StatusCode,
+		Protocol:   action.Protocol,
+		Host:       action.Host,
+		Port:       action.Port,
+		Path:       action.Path,
+		Query:      action.Query,
+	})
+
+	if *redirect == *lb.NewRedirectAction(lb.NewRedirectActionParams{StatusCode: action.StatusCode}) {
+		return nil, fmt.Errorf("a redirect action must change at least one of protocol, host, port, path or query")
+	}
+
+	return redirect, nil
+}
+
+func buildWeightedForwardAction(action Action, targetGroups map[string]*targetgroup.TargetGroup) (lb.Action, error) {
+	if action.TargetGroup != "" {
+		return nil, fmt.Errorf("a forward action must declare either target-group or target-groups")
+	}
+
+	weighted := make([]lb.WeightedTargetGroup, len(action.TargetGroups))
+	totalWeight := 0
+
+	for i, wtg := range action.TargetGroups {
+		targetGroup, err := findHttpTargetGroup(wtg.Name, targetGroups)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if wtg.Weight < 0 {
+			return nil, fmt.Errorf("target group %q has a negative weight", wtg.Name)
+		}
+
+		weighted[i] = lb.WeightedTargetGroup{TargetGroup: targetGroup, Weight: wtg.Weight}
+		totalWeight += wtg.Weight
+	}
+
+	if totalWeight == 0 {
+		return nil, fmt.Errorf("the weights of a forward action must add up to more than zero")
+	}
+
+	var stickinessDuration time.Duration
+
+	if action.Stickiness != nil && action.Stickiness.Enabled {
+		if action.Stickiness.Duration <= 0 {
+			return nil, fmt.Errorf("stickiness duration must be greater than zero")
+		}
+
+		stickinessDuration = time.Duration(action.Stickiness.Duration) * time.Second
+	}
+
+	return lb.NewWeightedForwardAction(weighted, stickinessDuration), nil
+}
+
+func buildRuleTable(rules []Rule, defaultAction *Action, targetGroups []*targetgroup.TargetGroup) (*lb.RuleTable, error) {
+	targetGroupsByName := make(map[string]*targetgroup.TargetGroup, len(targetGroups))
+
+	for _, tg := range targetGroups {
+		targetGroupsByName[tg.Name] = tg
+	}
+
+	priorities := make(map[int]bool, len(rules))
+	compiledRules := make([]*lb.Rule, 0, len(rules))
+
+	for _, rule := range rules {
+		if priorities[rule.Priority] {
+			return nil, fmt.Errorf("duplicate rule priority %d", rule.Priority)
+		}
+
+		priorities[rule.Priority] = true
+
+		conditions, err := buildConditions(rule.Conditions)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not build rule %d: %v", rule.Priority, err)
+		}
+
+		action, err := buildAction(rule.Action, targetGroupsByName)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not build rule %d: %v", rule.Priority, err)
+		}
+
+		compiledRules = append(compiledRules, lb.NewRule(rule.Priority, conditions, action))
+	}
+
+	var compiledDefaultAction lb.Action
+
+	if defaultAction != nil {
+		action, err := buildAction(*defaultAction, targetGroupsByName)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not build default action: %v", err)
+		}
+
+		compiledDefaultAction = action
+	} else {
+		for _, tg := range targetGroups {
+			if tg.Protocol == "http" || tg.Protocol == "https" {
+				compiledDefaultAction = lb.NewForwardAction(tg)
+				break
+			}
+		}
+	}
+
+	return lb.NewRuleTable(compiledRules, compiledDefaultAction), nil
+}
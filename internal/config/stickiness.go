@@ -0,0 +1,108 @@
+package config
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/algorithms"
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	"github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+const defaultStickinessDuration = 86400
+
+// TargetStickiness pins clients to a target of a target group, unlike
+// Stickiness which pins them to a target group of a weighted forward.
+type TargetStickiness struct {
+	Type       string `yaml:"type"`
+	Duration   int    `yaml:"duration,omitempty"`
+	Secret     string `yaml:"secret,omitempty"`
+	CookieName string `yaml:"cookie-name,omitempty"`
+	MaxEntries int    `yaml:"max-entries,omitempty"`
+}
+
+// getTargetGroupAlgorithm builds the algorithm of a target group, split into
+// its priority tiers and zones, and wrapped by its stickiness when configured.
+func (c *ConfigLoader) getTargetGroupAlgorithm(tg TargetGroup, localZone string, targets []*targetgroup.Target, proxyFactory proxy.ProxyFactory) (alg.Algorithm, error) {
+	if tg.Stickiness == nil {
+		return c.getTieredAlgorithm(tg, localZone, targets, proxyFactory)
+	}
+
+	if tg.Protocol == "tcp" || tg.Protocol == "udp" {
+		return nil, fmt.Errorf("stickiness is not supported with protocol %s", tg.Protocol)
+	}
+
+	duration := tg.Stickiness.Duration
+
+	if duration == 0 {
+		duration = defaultStickinessDuration
+	}
+
+	if duration < 0 {
+		return nil, fmt.Errorf("stickiness duration must be positive")
+	}
+
+	newAlgorithm := func(proxyFactory proxy.ProxyFactory) (alg.Algorithm, error) {
+		return c.getTieredAlgorithm(tg, localZone, targets, proxyFactory)
+	}
+
+	switch tg.Stickiness.Type {
+	case "lb-cookie":
+		secret, err := getStickinessSecret(tg.Stickiness.Secret)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sticky, err := algorithms.NewStickyCookie(targets, proxyFactory, newAlgorithm, algorithms.NewStickyCookieOptions{
+			Secret:   secret,
+			Duration: time.Duration(duration) * time.Second,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return sticky, nil
+	case "app-cookie":
+		if tg.Stickiness.CookieName == "" {
+			return nil, fmt.Errorf("stickiness type app-cookie requires a cookie-name")
+		}
+
+		if tg.Stickiness.MaxEntries < 0 {
+			return nil, fmt.Errorf("stickiness max-entries must be positive")
+		}
+
+		sticky, err := algorithms.NewAppCookie(targets, proxyFactory, newAlgorithm, algorithms.NewAppCookieOptions{
+			CookieName: tg.Stickiness.CookieName,
+			MaxEntries: tg.Stickiness.MaxEntries,
+			Duration:   time.Duration(duration) * time.Second,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return sticky, nil
+	}
+
+	return nil, fmt.Errorf("unknown stickiness type %q", tg.Stickiness.Type)
+}
+
+// getStickinessSecret returns the configured secret, or a random one which
+// invalidates the cookies issued before a restart.
+func getStickinessSecret(secret string) ([]byte, error) {
+	if secret != "" {
+		return []byte(secret), nil
+	}
+
+	random := make([]byte, 32)
+
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("could not generate stickiness secret: %v", err)
+	}
+
+	return random, nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"github.com/joaosczip/go-lb/internal/certificates"
+	"github.com/joaosczip/go-lb/internal/proxy"
+)
+
+type TargetTLS struct {
+	CAFile     string `yaml:"ca-file,omitempty"`
+	CertFile   string `yaml:"cert-file,omitempty"`
+	KeyFile    string `yaml:"key-file,omitempty"`
+	ServerName string `yaml:"server-name,omitempty"`
+}
+
+// upstream bundles how a target group reaches its targets, both for proxied
+// requests and for health checks.
+type upstream struct {
+	protocol     string
+	scheme       string
+	proxyFactory proxy.ProxyFactory
+	httpClient   *http.Client
+}
+
+func (c *ConfigLoader) getUpstream(tg TargetGroup) (upstream, error) {
+	if tg.ProxyProtocol != "" && tg.Protocol != "tcp" {
+		return upstream{}, fmt.Errorf("proxy-protocol is only supported with protocol tcp")
+	}
+
+	if tg.TLS != nil && tg.Protocol != "https" {
+		return upstream{}, fmt.Errorf("tls is only supported with protocol https")
+	}
+
+	switch tg.Protocol {
+	case "", "http":
+		return c.getHttpUpstream(tg)
+	case "https":
+		return getHttpsUpstream(tg)
+	case "tcp", "udp":
+		if tg.ProtocolVersion != "" {
+			return upstream{}, fmt.Errorf("protocol-version is not supported with protocol %s", tg.Protocol)
+		}
+
+		return upstream{
+			protocol:     tg.Protocol,
+			proxyFactory: c.proxyFactory,
+			httpClient:   c.httpClient,
+		}, nil
+	}
+
+	return upstream{}, fmt.Errorf("unknown protocol %q", tg.Protocol)
+}
+
+func (c *ConfigLoader) getHttpUpstream(tg TargetGroup) (upstream, error) {
+	switch tg.ProtocolVersion {
+	case "", "http1":
+		return upstream{
+			protocol:     "http",
+			proxyFactory: c.proxyFactory,
+			httpClient:   c.httpClient,
+		}, nil
+	case "h2c":
+		transport := proxy.NewTransport(proxy.NewTransportParams{ProtocolVersion: "h2c"})
+
+		return upstream{
+			protocol:     "http",
+			proxyFactory: proxy.NewTransportReverseProxyFactory("http", transport),
+			httpClient:   &http.Client{Transport: transport},
+		}, nil
+	case "http2":
+		return upstream{}, fmt.Errorf("protocol-version http2 requires protocol https, use h2c for cleartext HTTP/2")
+	}
+
+	return upstream{}, fmt.Errorf("unknown protocol-version %q", tg.ProtocolVersion)
+}
+
+func getHttpsUpstream(tg TargetGroup) (upstream, error) {
+	switch tg.ProtocolVersion {
+	case "", "http1", "http2":
+	case "h2c":
+		return upstream{}, fmt.Errorf("protocol-version h2c requires protocol http, use http2 over TLS")
+	default:
+		return upstream{}, fmt.Errorf("unknown protocol-version %q", tg.ProtocolVersion)
+	}
+
+	tlsConfig, err := buildUpstreamTLSConfig(tg.TLS)
+
+	if err != nil {
+		return upstream{}, err
+	}
+
+	transport := proxy.NewTransport(proxy.NewTransportParams{
+		TLSConfig:       tlsConfig,
+		ProtocolVersion: tg.ProtocolVersion,
+	})
+
+	return upstream{
+		protocol:     "https",
+		scheme:       "https",
+		proxyFactory: proxy.NewTransportReverseProxyFactory("https", transport),
+		httpClient:   &http.Client{Transport: transport},
+	}, nil
+}
+
+func buildUpstreamTLSConfig(targetTLS *TargetTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if targetTLS == nil {
+		return tlsConfig, nil
+	}
+
+	tlsConfig.ServerName = targetTLS.ServerName
+
+	if targetTLS.CAFile != "" {
+		pool, err := certificates.LoadCertPool(targetTLS.CAFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if (targetTLS.CertFile == "") != (targetTLS.KeyFile == "") {
+		return nil, fmt.Errorf("cert-file and key-file must be set together")
+	}
+
+	if targetTLS.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(targetTLS.CertFile, targetTLS.KeyFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate %s: %v", targetTLS.CertFile, err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/joaosczip/go-lb/internal/algorithms"
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	"github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+// getZonedAlgorithm builds one algorithm per zone when the load balancer
+// knows its zone and the targets span more than one, and a single algorithm
+// otherwise.
+func (c *ConfigLoader) getZonedAlgorithm(tg TargetGroup, localZone string, targets []*targetgroup.Target, proxyFactory proxy.ProxyFactory) (alg.Algorithm, error) {
+	zoned := false
+
+	for _, target := range targets {
+		if target.Zone != targets[0].Zone {
+			zoned = true
+		}
+	}
+
+	if localZone == "" || !zoned {
+		return c.getAlgorithm(targets, tg.Algorithm, proxyFactory)
+	}
+
+	if alg.PinsKeys(tg.Algorithm.Type) {
+		return nil, fmt.Errorf("zones are not supported with algorithm %s", tg.Algorithm.Type)
+	}
+
+	zoneAware, err := algorithms.NewZoneAware(targets, localZone, func(zoneTargets []*targetgroup.Target) (alg.Algorithm, error) {
+		return c.getAlgorithm(zoneTargets, tg.Algorithm, proxyFactory)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return zoneAware, nil
+}
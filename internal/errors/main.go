@@ -2,4 +2,8 @@ package errors
 
 import "errors"
 
-var ErrNoHealthyTargets = errors.New("no healthy targets available")
+var (
+	ErrNoHealthyTargets = errors.New("no healthy targets available")
+	ErrNoTargetGroups   = errors.New("no target groups available")
+	ErrUpgradeLimit     = errors.New("target reached its upgraded connections limit")
+)
@@ -0,0 +1,90 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// HealthCheckPath is the method of the gRPC health checking protocol.
+const HealthCheckPath = "/grpc.health.v1.Health/Check"
+
+// Serving is the status a healthy server reports for the checked service.
+const Serving = 1
+
+var ErrInvalidMessage = errors.New("invalid gRPC message")
+
+// EncodeHealthCheckRequest frames a grpc.health.v1.HealthCheckRequest for the
+// given service; an empty service asks for the overall server health.
+func EncodeHealthCheckRequest(service string) []byte {
+	var message []byte
+
+	if service != "" {
+		message = append(message, 0x0a)
+		message = binary.AppendUvarint(message, uint64(len(service)))
+		message = append(message, service...)
+	}
+
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+
+	return append(frame, message...)
+}
+
+// DecodeHealthCheckResponse reads the status of a framed
+// grpc.health.v1.HealthCheckResponse.
+func DecodeHealthCheckResponse(frame []byte) (int, error) {
+	if len(frame) < 5 {
+		return 0, ErrInvalidMessage
+	}
+
+	if frame[0] != 0 {
+		return 0, fmt.Errorf("compressed health check responses are not supported")
+	}
+
+	length := binary.BigEndian.Uint32(frame[1:5])
+	message := frame[5:]
+
+	if uint32(len(message)) != length {
+		return 0, ErrInvalidMessage
+	}
+
+	status := 0
+
+	for len(message) > 0 {
+		tag, n := binary.Uvarint(message)
+
+		if n <= 0 {
+			return 0, ErrInvalidMessage
+		}
+
+		message = message[n:]
+
+		switch tag & 0x7 {
+		case 0:
+			value, n := binary.Uvarint(message)
+
+			if n <= 0 {
+				return 0, ErrInvalidMessage
+			}
+
+			if tag>>3 == 1 {
+				status = int(value)
+			}
+
+			message = message[n:]
+		case 2:
+			size, n := binary.Uvarint(message)
+
+			if n <= 0 || uint64(len(message)-n) < size {
+				return 0, ErrInvalidMessage
+			}
+
+			message = message[n+int(size):]
+		default:
+			return 0, ErrInvalidMessage
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,37 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheckMessages(t *testing.T) {
+	t.Run("Should frame a request for the given service", func(t *testing.T) {
+		assert.Equal(t, []byte{0, 0, 0, 0, 5, 0x0a, 3, 'a', 'p', 'i'}, EncodeHealthCheckRequest("api"))
+	})
+
+	t.Run("Should frame an empty request for the overall server health", func(t *testing.T) {
+		assert.Equal(t, []byte{0, 0, 0, 0, 0}, EncodeHealthCheckRequest(""))
+	})
+
+	t.Run("Should decode the serving status of a response", func(t *testing.T) {
+		status, err := DecodeHealthCheckResponse([]byte{0, 0, 0, 0, 2, 0x08, 1})
+
+		assert.NoError(t, err)
+		assert.Equal(t, Serving, status)
+	})
+
+	t.Run("Should skip unknown fields of a response", func(t *testing.T) {
+		status, err := DecodeHealthCheckResponse([]byte{0, 0, 0, 0, 5, 0x12, 1, 'x', 0x08, 2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, status)
+	})
+
+	t.Run("Should return an error when the frame is truncated", func(t *testing.T) {
+		_, err := DecodeHealthCheckResponse([]byte{0, 0, 0, 0, 2, 0x08})
+
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+	})
+}
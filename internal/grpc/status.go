@@ -0,0 +1,63 @@
+package grpc
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Status codes from the gRPC specification that the load balancer reports
+// itself.
+const (
+	Unknown          = 2
+	PermissionDenied = 7
+	Unimplemented    = 12
+	Internal         = 13
+	Unavailable      = 14
+	Unauthenticated  = 16
+)
+
+// IsRequest reports whether r is a gRPC call.
+func IsRequest(r *http.Request) bool {
+	return IsContentType(r.Header.Get("Content-Type"))
+}
+
+// IsContentType reports whether contentType is one of the gRPC content types,
+// such as application/grpc or application/grpc+proto.
+func IsContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/grpc")
+}
+
+// WriteError answers a gRPC call with a trailers-only response, which is how
+// gRPC servers report a failure before any message was sent.
+func WriteError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", encodeMessage(message))
+	w.WriteHeader(http.StatusOK)
+}
+
+// CodeFromHttpStatus maps the status of a response that did not come from a
+// gRPC server, as described in the gRPC HTTP to gRPC status code mapping.
+func CodeFromHttpStatus(status int) int {
+	switch status {
+	case http.StatusBadRequest:
+		return Internal
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusForbidden:
+		return PermissionDenied
+	case http.StatusNotFound:
+		return Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return Unavailable
+	}
+
+	return Unknown
+}
+
+// encodeMessage percent-encodes the message as required for grpc-message.
+func encodeMessage(message string) string {
+	return strings.ReplaceAll(url.PathEscape(message), "%20", " ")
+}
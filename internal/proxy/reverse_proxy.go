@@ -1,19 +1,21 @@
 package proxy
 
 import (
+	"crypto/tls"
 	"fmt"
+	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
+
+	"github.com/joaosczip/go-lb/internal/grpc"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
 )
 
-type Proxy interface {
-	ServeHTTP(w http.ResponseWriter, req *http.Request)
-}
+type Proxy = alg.Proxy
 
-type ProxyFactory interface {
-	Create(host string, port int) Proxy
-}
+type ProxyFactory = alg.ProxyFactory
 
 type HttpProxy struct {
 	proxy *httputil.ReverseProxy
@@ -23,17 +25,108 @@ func (p *HttpProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	p.proxy.ServeHTTP(w, req)
 }
 
-type reverseProxyFactory struct{}
+type reverseProxyFactory struct {
+	scheme    string
+	transport http.RoundTripper
+}
 
 func NewReverseProxyFactory() ProxyFactory {
-	return &reverseProxyFactory{}
+	return &reverseProxyFactory{
+		scheme: "http",
+	}
+}
+
+// NewTransportReverseProxyFactory creates proxies that reach their targets
+// with the given scheme, sharing a single transport.
+func NewTransportReverseProxyFactory(scheme string, transport http.RoundTripper) ProxyFactory {
+	return &reverseProxyFactory{
+		scheme:    scheme,
+		transport: transport,
+	}
+}
+
+// NewTransportParams configures an upstream transport. ProtocolVersion is
+// one of http1, http2 (over TLS) or h2c (cleartext HTTP/2 with prior
+// knowledge); an empty value keeps the default negotiation.
+type NewTransportParams struct {
+	TLSConfig       *tls.Config
+	ProtocolVersion string
+}
+
+func NewTransport(params NewTransportParams) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = params.TLSConfig
+
+	var protocols http.Protocols
+
+	switch params.ProtocolVersion {
+	case "http1":
+		protocols.SetHTTP1(true)
+	case "http2":
+		protocols.SetHTTP2(true)
+	case "h2c":
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		return transport
+	}
+
+	transport.Protocols = &protocols
+
+	return transport
 }
 
 func (f *reverseProxyFactory) Create(host string, port int) Proxy {
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{
+		Scheme: f.scheme,
+		Host:   fmt.Sprintf("%s:%d", host, port),
+	})
+
+	if f.transport != nil {
+		proxy.Transport = f.transport
+	}
+
+	proxy.ModifyResponse = modifyGrpcResponse
+	proxy.ErrorHandler = handleProxyError
+
 	return &HttpProxy{
-		proxy: httputil.NewSingleHostReverseProxy(&url.URL{
-			Scheme: "http",
-			Host:   fmt.Sprintf("%s:%d", host, port),
-		}),
+		proxy: proxy,
 	}
 }
+
+// modifyGrpcResponse turns a response to a gRPC call that did not come from a
+// gRPC server (an error page from a target or a sidecar, for instance) into
+// a gRPC status the client can understand.
+func modifyGrpcResponse(res *http.Response) error {
+	if !grpc.IsRequest(res.Request) || res.Header.Get("Grpc-Status") != "" {
+		return nil
+	}
+
+	if res.StatusCode == http.StatusOK && grpc.IsContentType(res.Header.Get("Content-Type")) {
+		return nil
+	}
+
+	res.Body.Close()
+
+	code := grpc.CodeFromHttpStatus(res.StatusCode)
+
+	res.Header = http.Header{}
+	res.Header.Set("Content-Type", "application/grpc")
+	res.Header.Set("Grpc-Status", strconv.Itoa(code))
+	res.Header.Set("Grpc-Message", fmt.Sprintf("target responded with status %d", res.StatusCode))
+	res.StatusCode = http.StatusOK
+	res.ContentLength = 0
+	res.Body = http.NoBody
+
+	return nil
+}
+
+func handleProxyError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("http: proxy error: %v", err)
+
+	if grpc.IsRequest(r) {
+		grpc.WriteError(w, grpc.Unavailable, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusBadGateway)
+}
@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReverseProxy_Grpc(t *testing.T) {
+	t.Run("Should answer gRPC calls with UNAVAILABLE when the target cannot be reached", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		port := ln.Addr().(*net.TCPAddr).Port
+		ln.Close()
+
+		proxy := NewReverseProxyFactory().Create("127.0.0.1", port)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "http://localhost/orders.v1.Orders/Create", nil)
+		r.Header.Set("Content-Type", "application/grpc")
+
+		proxy.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "14", w.Header().Get("Grpc-Status"))
+	})
+
+	t.Run("Should map an HTTP error from the target to a gRPC status", func(t *testing.T) {
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "not found", http.StatusNotFound)
+		}))
+		defer target.Close()
+
+		addr := target.Listener.Addr().(*net.TCPAddr)
+		proxy := NewReverseProxyFactory().Create("127.0.0.1", addr.Port)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "http://localhost/orders.v1.Orders/Create", nil)
+		r.Header.Set("Content-Type", "application/grpc")
+
+		proxy.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "12", w.Header().Get("Grpc-Status"))
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("Should keep the bad gateway response for other requests", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		port := ln.Addr().(*net.TCPAddr).Port
+		ln.Close()
+
+		proxy := NewReverseProxyFactory().Create("127.0.0.1", port)
+
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/", nil))
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+}
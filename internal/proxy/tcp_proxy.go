@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxyproto"
+)
+
+const (
+	defaultDialTimeout = 5 * time.Second
+	tcpBufferSize      = 32 * 1024
+)
+
+type TCPProxy struct {
+	idleTimeout          time.Duration
+	dialTimeout          time.Duration
+	proxyProtocolVersion int
+}
+
+// NewTCPProxyParams configures a TCP proxy. The connection is closed once no
+// byte went either way for IdleTimeout, and a zero IdleTimeout disables the
+// idle check. When ProxyProtocolVersion is 1 or 2, the client address is
+// announced to the target with a PROXY protocol header.
+type NewTCPProxyParams struct {
+	IdleTimeout          time.Duration
+	ProxyProtocolVersion int
+}
+
+func NewTCPProxy(params NewTCPProxyParams) *TCPProxy {
+	return &TCPProxy{
+		idleTimeout:          params.IdleTimeout,
+		dialTimeout:          defaultDialTimeout,
+		proxyProtocolVersion: params.ProxyProtocolVersion,
+	}
+}
+
+func (p *TCPProxy) ServeConn(conn net.Conn, host string, port int) error {
+	upstream, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), p.dialTimeout)
+
+	if err != nil {
+		return fmt.Errorf("could not connect to target %s:%d: %v", host, port, err)
+	}
+
+	defer upstream.Close()
+
+	if p.proxyProtocolVersion != 0 {
+		err = proxyproto.WriteHeader(upstream, p.proxyProtocolVersion, conn.RemoteAddr(), conn.LocalAddr())
+
+		if err != nil {
+			return fmt.Errorf("could not send PROXY protocol header to target %s:%d: %v", host, port, err)
+		}
+	}
+
+	return newSplice(p.idleTimeout, tcpBufferSize).run(conn, upstream)
+}
+
+type splice struct {
+	idleTimeout  time.Duration
+	bufferSize   int
+	lastActivity atomic.Int64
+	// closing is set once a direction closes both connections, so the
+	// errors the other direction then gets are not reported.
+	closing atomic.Bool
+}
+
+// newSplice relays reads of up to bufferSize bytes, which for packet flows
+// must hold a whole datagram since a short read truncates it.
+func newSplice(idleTimeout time.Duration, bufferSize int) *splice {
+	s := &splice{idleTimeout: idleTimeout, bufferSize: bufferSize}
+	s.touch()
+
+	return s
+}
+
+func (s *splice) touch() {
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+func (s *splice) idleFor() time.Duration {
+	return time.Since(time.Unix(0, s.lastActivity.Load()))
+}
+
+func (s *splice) run(client, upstream net.Conn) error {
+	var wg sync.WaitGroup
+	errCh := make(chan error, 2)
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		errCh <- s.copy(upstream, client)
+	}()
+
+	go func() {
+		defer wg.Done()
+		errCh <- s.copy(client, upstream)
+	}()
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copy relays src to dst. A read timeout only ends the copy when the whole
+// connection has been idle, so a long one-way stream keeps both directions
+// open.
+func (s *splice) copy(dst, src net.Conn) error {
+	buf := make([]byte, s.bufferSize)
+
+	for {
+		if s.idleTimeout > 0 {
+			src.SetReadDeadline(time.Now().Add(s.idleTimeout))
+		}
+
+		n, err := src.Read(buf)
+
+		if n > 0 {
+			s.touch()
+
+			if s.idleTimeout > 0 {
+				dst.SetWriteDeadline(time.Now().Add(s.idleTimeout))
+			}
+
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				if s.close(dst, src) {
+					return nil
+				}
+
+				return werr
+			}
+		}
+
+		if err == nil {
+			continue
+		}
+
+		var netErr net.Error
+
+		if errors.As(err, &netErr) && netErr.Timeout() && s.idleFor() < s.idleTimeout {
+			continue
+		}
+
+		if errors.Is(err, io.EOF) {
+			closeWrite(dst)
+			return nil
+		}
+
+		if s.close(dst, src) {
+			return nil
+		}
+
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil
+		}
+
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+
+		return err
+	}
+}
+
+// close closes both connections, reporting whether the other direction
+// already had.
+func (s *splice) close(dst, src net.Conn) bool {
+	closed := s.closing.Swap(true)
+	closeBoth(dst, src)
+
+	return closed
+}
+
+func closeWrite(conn net.Conn) {
+	if halfCloser, ok := conn.(interface{ CloseWrite() error }); ok {
+		halfCloser.CloseWrite()
+		return
+	}
+
+	conn.Close()
+}
+
+func closeBoth(a, b net.Conn) {
+	a.Close()
+	b.Close()
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func startEchoServer(t *testing.T) *net.TCPAddr {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr)
+}
+
+func TestTCPProxy_ServeConn(t *testing.T) {
+	t.Run("Should splice bytes both ways between the client and the target", func(t *testing.T) {
+		addr := startEchoServer(t)
+		client, server := net.Pipe()
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- NewTCPProxy(NewTCPProxyParams{IdleTimeout: time.Second}).ServeConn(server, "127.0.0.1", addr.Port)
+		}()
+
+		_, err := client.Write([]byte("ping\n"))
+		assert.NoError(t, err)
+
+		line, err := bufio.NewReader(client).ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "ping\n", line)
+
+		client.Close()
+
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("Should close the connection once it has been idle for the idle timeout", func(t *testing.T) {
+		addr := startEchoServer(t)
+		client, server := net.Pipe()
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- NewTCPProxy(NewTCPProxyParams{IdleTimeout: 50 * time.Millisecond}).ServeConn(server, "127.0.0.1", addr.Port)
+		}()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("the idle connection was not closed")
+		}
+
+		_, err := client.Read(make([]byte, 1))
+		assert.Error(t, err)
+	})
+
+	t.Run("Should return an error when the target cannot be reached", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		port := ln.Addr().(*net.TCPAddr).Port
+		ln.Close()
+
+		_, server := net.Pipe()
+
+		err = NewTCPProxy(NewTCPProxyParams{IdleTimeout: time.Second}).ServeConn(server, "127.0.0.1", port)
+
+		assert.ErrorContains(t, err, "could not connect to target")
+	})
+}
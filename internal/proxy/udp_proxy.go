@@ -0,0 +1,36 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// MaxDatagramSize is the largest datagram relayed in either direction.
+const MaxDatagramSize = 64 * 1024
+
+type UDPProxy struct {
+	idleTimeout time.Duration
+}
+
+// NewUDPProxy creates a proxy that relays the datagrams of a client flow to
+// its target and the replies back, until the flow has been idle for
+// idleTimeout.
+func NewUDPProxy(idleTimeout time.Duration) *UDPProxy {
+	return &UDPProxy{
+		idleTimeout: idleTimeout,
+	}
+}
+
+func (p *UDPProxy) ServeConn(conn net.Conn, host string, port int) error {
+	upstream, err := net.Dial("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+
+	if err != nil {
+		return fmt.Errorf("could not connect to target %s:%d: %v", host, port, err)
+	}
+
+	defer upstream.Close()
+
+	return newSplice(p.idleTimeout, MaxDatagramSize).run(conn, upstream)
+}
@@ -0,0 +1,263 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+var (
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+	ErrNoHeader      = errors.New("missing PROXY protocol header")
+	ErrInvalidHeader = errors.New("invalid PROXY protocol header")
+)
+
+const (
+	v1MaxLength = 107
+
+	v2CommandLocal = 0x20
+	v2CommandProxy = 0x21
+
+	v2FamilyUnspec   = 0x00
+	v2FamilyTCP4     = 0x11
+	v2FamilyUDP4     = 0x12
+	v2FamilyTCP6     = 0x21
+	v2FamilyUDP6     = 0x22
+	v2AddressLength4 = 12
+	v2AddressLength6 = 36
+)
+
+// Header carries the addresses announced by a PROXY protocol header. Both
+// are nil when the sender did not relay a client (v1 UNKNOWN, v2 LOCAL).
+type Header struct {
+	Source      net.Addr
+	Destination net.Addr
+}
+
+// ReadHeader parses a v1 or v2 header from the start of r.
+func ReadHeader(r *bufio.Reader) (*Header, error) {
+	prefix, err := r.Peek(len(v2Signature))
+
+	if err != nil && len(prefix) < 6 {
+		return nil, ErrNoHeader
+	}
+
+	if bytes.Equal(prefix, v2Signature) {
+		return readV2(r)
+	}
+
+	if bytes.HasPrefix(prefix, []byte("PROXY ")) {
+		return readV1(r)
+	}
+
+	return nil, ErrNoHeader
+}
+
+func readV1(r *bufio.Reader) (*Header, error) {
+	line := make([]byte, 0, v1MaxLength)
+
+	for {
+		b, err := r.ReadByte()
+
+		if err != nil {
+			return nil, ErrInvalidHeader
+		}
+
+		line = append(line, b)
+
+		if b == '\n' {
+			break
+		}
+
+		if len(line) == v1MaxLength {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrInvalidHeader
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return &Header{}, nil
+	}
+
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, ErrInvalidHeader
+	}
+
+	source, err := parseV1Addr(fields[2], fields[4])
+
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := parseV1Addr(fields[3], fields[5])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Header{Source: source, Destination: destination}, nil
+}
+
+func parseV1Addr(ip, port string) (net.Addr, error) {
+	addr, err := netip.ParseAddr(ip)
+
+	if err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+
+	if err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(p))), nil
+}
+
+func readV2(r *bufio.Reader) (*Header, error) {
+	fixed := make([]byte, 16)
+
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	command := fixed[12]
+	family := fixed[13]
+	length := binary.BigEndian.Uint16(fixed[14:16])
+
+	payload := make([]byte, length)
+
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	switch command {
+	case v2CommandLocal:
+		return &Header{}, nil
+	case v2CommandProxy:
+	default:
+		return nil, ErrInvalidHeader
+	}
+
+	switch family {
+	case v2FamilyTCP4, v2FamilyUDP4:
+		if len(payload) < v2AddressLength4 {
+			return nil, ErrInvalidHeader
+		}
+
+		return &Header{
+			Source:      v2Addr(family, netip.AddrFrom4([4]byte(payload[0:4])), payload[8:10]),
+			Destination: v2Addr(family, netip.AddrFrom4([4]byte(payload[4:8])), payload[10:12]),
+		}, nil
+	case v2FamilyTCP6, v2FamilyUDP6:
+		if len(payload) < v2AddressLength6 {
+			return nil, ErrInvalidHeader
+		}
+
+		return &Header{
+			Source:      v2Addr(family, netip.AddrFrom16([16]byte(payload[0:16])), payload[32:34]),
+			Destination: v2Addr(family, netip.AddrFrom16([16]byte(payload[16:32])), payload[34:36]),
+		}, nil
+	}
+
+	return &Header{}, nil
+}
+
+func v2Addr(family byte, ip netip.Addr, port []byte) net.Addr {
+	addrPort := netip.AddrPortFrom(ip, binary.BigEndian.Uint16(port))
+
+	if family == v2FamilyUDP4 || family == v2FamilyUDP6 {
+		return net.UDPAddrFromAddrPort(addrPort)
+	}
+
+	return net.TCPAddrFromAddrPort(addrPort)
+}
+
+// WriteHeader announces the client at source, connected to destination,
+// using the given protocol version (1 or 2). Addresses that are not TCP or of
+// different families are sent as UNKNOWN (v1) or LOCAL (v2).
+func WriteHeader(w io.Writer, version int, source, destination net.Addr) error {
+	src, srcOk := tcpAddrPort(source)
+	dst, dstOk := tcpAddrPort(destination)
+	known := srcOk && dstOk && src.Addr().Is4() == dst.Addr().Is4()
+
+	switch version {
+	case 1:
+		return writeV1(w, known, src, dst)
+	case 2:
+		return writeV2(w, known, src, dst)
+	}
+
+	return fmt.Errorf("unknown PROXY protocol version %d", version)
+}
+
+func tcpAddrPort(addr net.Addr) (netip.AddrPort, bool) {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+
+	if !ok {
+		return netip.AddrPort{}, false
+	}
+
+	addrPort := tcpAddr.AddrPort()
+
+	return netip.AddrPortFrom(addrPort.Addr().Unmap(), addrPort.Port()), true
+}
+
+func writeV1(w io.Writer, known bool, src, dst netip.AddrPort) error {
+	if !known {
+		_, err := io.WriteString(w, "PROXY UNKNOWN\r\n")
+		return err
+	}
+
+	family := "TCP4"
+
+	if src.Addr().Is6() {
+		family = "TCP6"
+	}
+
+	_, err := fmt.Fprintf(w, "PROXY %s %s %s %d %d\r\n", family, src.Addr(), dst.Addr(), src.Port(), dst.Port())
+
+	return err
+}
+
+func writeV2(w io.Writer, known bool, src, dst netip.AddrPort) error {
+	header := append([]byte{}, v2Signature...)
+
+	if !known {
+		header = append(header, v2CommandLocal, v2FamilyUnspec, 0, 0)
+
+		_, err := w.Write(header)
+
+		return err
+	}
+
+	if src.Addr().Is4() {
+		header = append(header, v2CommandProxy, v2FamilyTCP4)
+		header = binary.BigEndian.AppendUint16(header, v2AddressLength4)
+	} else {
+		header = append(header, v2CommandProxy, v2FamilyTCP6)
+		header = binary.BigEndian.AppendUint16(header, v2AddressLength6)
+	}
+
+	header = append(header, src.Addr().AsSlice()...)
+	header = append(header, dst.Addr().AsSlice()...)
+	header = binary.BigEndian.AppendUint16(header, src.Port())
+	header = binary.BigEndian.AppendUint16(header, dst.Port())
+
+	_, err := w.Write(header)
+
+	return err
+}
@@ -0,0 +1,132 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadHeader(t *testing.T) {
+	t.Run("Should parse a v1 header and leave the payload untouched", func(t *testing.T) {
+		r := bufio.NewReader(strings.NewReader("PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\nGET / HTTP/1.1\r\n"))
+
+		header, err := ReadHeader(r)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "203.0.113.7:51234", header.Source.String())
+		assert.Equal(t, "10.0.0.1:443", header.Destination.String())
+
+		rest, _ := io.ReadAll(r)
+		assert.Equal(t, "GET / HTTP/1.1\r\n", string(rest))
+	})
+
+	t.Run("Should parse a v1 UNKNOWN header without addresses", func(t *testing.T) {
+		header, err := ReadHeader(bufio.NewReader(strings.NewReader("PROXY UNKNOWN\r\n")))
+
+		assert.NoError(t, err)
+		assert.Nil(t, header.Source)
+	})
+
+	t.Run("Should reject a connection without a header", func(t *testing.T) {
+		_, err := ReadHeader(bufio.NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: x\r\n")))
+
+		assert.ErrorIs(t, err, ErrNoHeader)
+	})
+
+	t.Run("Should reject a malformed v1 header", func(t *testing.T) {
+		_, err := ReadHeader(bufio.NewReader(strings.NewReader("PROXY TCP4 not-an-ip 10.0.0.1 1 2\r\n")))
+
+		assert.ErrorIs(t, err, ErrInvalidHeader)
+	})
+}
+
+func TestWriteHeader(t *testing.T) {
+	source := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 51234}
+	destination := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 443}
+
+	t.Run("Should write a v1 header", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		assert.NoError(t, WriteHeader(&buf, 1, source, destination))
+		assert.Equal(t, "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\n", buf.String())
+	})
+
+	for _, version := range []int{1, 2} {
+		t.Run(fmt.Sprintf("Should read back the addresses it writes with version %d", version), func(t *testing.T) {
+			var buf bytes.Buffer
+
+			assert.NoError(t, WriteHeader(&buf, version, source, destination))
+
+			header, err := ReadHeader(bufio.NewReader(&buf))
+
+			assert.NoError(t, err)
+			assert.Equal(t, source.String(), header.Source.String())
+			assert.Equal(t, destination.String(), header.Destination.String())
+		})
+	}
+
+	t.Run("Should read back IPv6 addresses from a v2 header", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		source6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::7"), Port: 51234}
+		destination6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443}
+
+		assert.NoError(t, WriteHeader(&buf, 2, source6, destination6))
+
+		header, err := ReadHeader(bufio.NewReader(&buf))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "[2001:db8::7]:51234", header.Source.String())
+	})
+
+	t.Run("Should send a LOCAL v2 header when the addresses are unknown", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		assert.NoError(t, WriteHeader(&buf, 2, &net.UnixAddr{Name: "/tmp/sock"}, destination))
+
+		header, err := ReadHeader(bufio.NewReader(&buf))
+
+		assert.NoError(t, err)
+		assert.Nil(t, header.Source)
+	})
+}
+
+func TestListener(t *testing.T) {
+	t.Run("Should expose the announced client as the remote address", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer ln.Close()
+
+		proxyLn := NewListener(ln, time.Second)
+
+		go func() {
+			conn, err := net.Dial("tcp", ln.Addr().String())
+
+			if err != nil {
+				return
+			}
+
+			defer conn.Close()
+
+			io.WriteString(conn, "PROXY TCP4 198.51.100.9 10.0.0.1 40000 80\r\nhello")
+		}()
+
+		conn, err := proxyLn.Accept()
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		assert.Equal(t, "198.51.100.9:40000", conn.RemoteAddr().String())
+
+		payload, err := io.ReadAll(conn)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(payload))
+	})
+}
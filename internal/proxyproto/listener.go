@@ -0,0 +1,101 @@
+package proxyproto
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+)
+
+type Listener struct {
+	net.Listener
+	headerTimeout time.Duration
+}
+
+// NewListener wraps ln so that every accepted connection must start with a
+// PROXY protocol header. The announced client then becomes the RemoteAddr of
+// the connection.
+func NewListener(ln net.Listener, headerTimeout time.Duration) *Listener {
+	return &Listener{
+		Listener:      ln,
+		headerTimeout: headerTimeout,
+	}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(conn, l.headerTimeout), nil
+}
+
+// Conn reads the header lazily, on the first Read or address lookup, so a
+// slow client never blocks the accept loop.
+type Conn struct {
+	net.Conn
+	reader        *bufio.Reader
+	headerTimeout time.Duration
+	once          sync.Once
+	header        *Header
+	headerErr     error
+}
+
+func newConn(conn net.Conn, headerTimeout time.Duration) *Conn {
+	return &Conn{
+		Conn:          conn,
+		reader:        bufio.NewReader(conn),
+		headerTimeout: headerTimeout,
+	}
+}
+
+func (c *Conn) readHeader() {
+	c.once.Do(func() {
+		if c.headerTimeout > 0 {
+			c.Conn.SetReadDeadline(time.Now().Add(c.headerTimeout))
+			defer c.Conn.SetReadDeadline(time.Time{})
+		}
+
+		c.header, c.headerErr = ReadHeader(c.reader)
+	})
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	c.readHeader()
+
+	if c.headerErr != nil {
+		return 0, c.headerErr
+	}
+
+	return c.reader.Read(b)
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	c.readHeader()
+
+	if c.header != nil && c.header.Source != nil {
+		return c.header.Source
+	}
+
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	c.readHeader()
+
+	if c.header != nil && c.header.Destination != nil {
+		return c.header.Destination
+	}
+
+	return c.Conn.LocalAddr()
+}
+
+func (c *Conn) CloseWrite() error {
+	if halfCloser, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return halfCloser.CloseWrite()
+	}
+
+	return c.Conn.Close()
+}
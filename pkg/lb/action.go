@@ -0,0 +1,286 @@
+package lb
+
+import (
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+	tg "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+type Action interface {
+	Handle(w http.ResponseWriter, r *http.Request) error
+}
+
+type ForwardAction struct {
+	TargetGroup *tg.TargetGroup
+}
+
+func NewForwardAction(targetGroup *tg.TargetGroup) *ForwardAction {
+	return &ForwardAction{
+		TargetGroup: targetGroup,
+	}
+}
+
+func (a *ForwardAction) Handle(w http.ResponseWriter, r *http.Request) error {
+	return a.TargetGroup.Algorithm.Handle(w, r)
+}
+
+const targetGroupStickinessCookie = "GOLBTG"
+
+type WeightedTargetGroup struct {
+	TargetGroup *tg.TargetGroup
+	Weight      int
+}
+
+type WeightedForwardAction struct {
+	TargetGroups       []WeightedTargetGroup
+	StickinessDuration time.Duration
+	totalWeight        int
+	randIntn           func(n int) int
+}
+
+// NewWeightedForwardAction splits traffic between the target groups in
+// proportion to their weights. When stickinessDuration is positive, the
+// client is pinned to the first group it landed in through a cookie.
+func NewWeightedForwardAction(targetGroups []WeightedTargetGroup, stickinessDuration time.Duration) *WeightedForwardAction {
+	totalWeight := 0
+
+	for _, targetGroup := range targetGroups {
+		totalWeight += targetGroup.Weight
+	}
+
+	return &WeightedForwardAction{
+		TargetGroups:       targetGroups,
+		StickinessDuration: stickinessDuration,
+		totalWeight:        totalWeight,
+		randIntn:           rand.IntN,
+	}
+}
+
+func (a *WeightedForwardAction) stickyTargetGroup(r *http.Request) *tg.TargetGroup {
+	if a.StickinessDuration <= 0 {
+		return nil
+	}
+
+	cookie, err := r.Cookie(targetGroupStickinessCookie)
+
+	if err != nil {
+		return nil
+	}
+
+	for _, targetGroup := range a.TargetGroups {
+		if targetGroup.Weight > 0 && targetGroup.TargetGroup.Name == cookie.Value {
+			return targetGroup.TargetGroup
+		}
+	}
+
+	return nil
+}
+
+func (a *WeightedForwardAction) pickTargetGroup() *tg.TargetGroup {
+	n := a.randIntn(a.totalWeight)
+
+	for _, targetGroup := range a.TargetGroups {
+		if n < targetGroup.Weight {
+			return targetGroup.TargetGroup
+		}
+
+		n -= targetGroup.Weight
+	}
+
+	return nil
+}
+
+func (a *WeightedForwardAction) Handle(w http.ResponseWriter, r *http.Request) error {
+	if a.totalWeight <= 0 {
+		return errs.ErrNoTargetGroups
+	}
+
+	targetGroup := a.stickyTargetGroup(r)
+
+	if targetGroup == nil {
+		targetGroup = a.pickTargetGroup()
+	}
+
+	if a.StickinessDuration > 0 {
+		http.SetCookie(w, &http.Cookie{
+			Name:     targetGroupStickinessCookie,
+			Value:    targetGroup.Name,
+			Path:     "/",
+			MaxAge:   int(a.StickinessDuration.Seconds()),
+			HttpOnly: true,
+		})
+	}
+
+	return targetGroup.Algorithm.Handle(w, r)
+}
+
+type FixedResponseAction struct {
+	StatusCode  int
+	ContentType string
+	MessageBody string
+}
+
+func NewFixedResponseAction(statusCode int, contentType, messageBody string) *FixedResponseAction {
+	return &FixedResponseAction{
+		StatusCode:  statusCode,
+		ContentType: contentType,
+		MessageBody: messageBody,
+	}
+}
+
+func (a *FixedResponseAction) Handle(w http.ResponseWriter, r *http.Request) error {
+	if a.ContentType != "" {
+		w.Header().Set("Content-Type", a.ContentType)
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(a.MessageBody)))
+	w.WriteHeader(a.StatusCode)
+
+	if r.Method != http.MethodHead {
+		io.WriteString(w, a.MessageBody)
+	}
+
+	return nil
+}
+
+type RedirectAction struct {
+	StatusCode int
+	Protocol   string
+	Host       string
+	Port       string
+	Path       string
+	Query      string
+}
+
+// NewRedirectActionParams holds the components of the redirect location. Each
+// component may reference the original request through the #{protocol},
+// #{host}, #{port}, #{path} and #{query} placeholders, and an empty component
+// keeps the original value.
+type NewRedirectActionParams struct {
+	StatusCode int
+	Protocol   string
+	Host       string
+	Port       string
+	Path       string
+	Query      string
+}
+
+func NewRedirectAction(params NewRedirectActionParams) *RedirectAction {
+	return &RedirectAction{
+		StatusCode: params.StatusCode,
+		Protocol:   orDefault(params.Protocol, "#{protocol}"),
+		Host:       orDefault(params.Host, "#{host}"),
+		Port:       orDefault(params.Port, "#{port}"),
+		Path:       orDefault(params.Path, "#{path}"),
+		Query:      orDefault(params.Query, "#{query}"),
+	}
+}
+
+func (a *RedirectAction) Handle(w http.ResponseWriter, r *http.Request) error {
+	http.Redirect(w, r, a.location(r), a.StatusCode)
+	return nil
+}
+
+func (a *RedirectAction) location(r *http.Request) string {
+	protocol, host, port := requestOrigin(r)
+
+	replacer := strings.NewReplacer(
+		"#{protocol}", protocol,
+		"#{host}", host,
+		"#{port}", port,
+		"#{path}", r.URL.Path,
+		"#{query}", r.URL.RawQuery,
+	)
+
+	location := url.URL{
+		Scheme:   strings.ToLower(replacer.Replace(a.Protocol)),
+		Host:     replacer.Replace(a.Host),
+		Path:     replacer.Replace(a.Path),
+		RawQuery: joinQuery(replacer.Replace(a.Query)),
+	}
+
+	redirectPort := replacer.Replace(a.Port)
+
+	// Without a configured port, the default port of the original protocol
+	// means "the default port" rather than a port to keep: http on 80
+	// redirected to https lands on 443.
+	if a.Port == "#{port}" && location.Scheme != protocol && port == defaultPort(protocol) {
+		redirectPort = defaultPort(location.Scheme)
+	}
+
+	if redirectPort != defaultPort(location.Scheme) {
+		location.Host = net.JoinHostPort(location.Host, redirectPort)
+	} else if strings.Contains(location.Host, ":") {
+		location.Host = "[" + location.Host + "]"
+	}
+
+	if !strings.HasPrefix(location.Path, "/") {
+		location.Path = "/" + location.Path
+	}
+
+	return location.String()
+}
+
+// joinQuery drops the empty parts a template leaves behind, as with
+// "#{query}&from=legacy" on a request without a query.
+func joinQuery(query string) string {
+	var parts []string
+
+	for _, part := range strings.Split(query, "&") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+func requestOrigin(r *http.Request) (protocol, host, port string) {
+	protocol = "http"
+
+	if r.TLS != nil {
+		protocol = "https"
+	}
+
+	host, port, err := net.SplitHostPort(r.Host)
+
+	if err == nil {
+		return protocol, host, port
+	}
+
+	// A bare IPv6 host keeps its brackets, which JoinHostPort adds again.
+	host = strings.TrimSuffix(strings.TrimPrefix(r.Host, "["), "]")
+	port = defaultPort(protocol)
+
+	if localAddr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		if _, localPort, err := net.SplitHostPort(localAddr.String()); err == nil {
+			port = localPort
+		}
+	}
+
+	return protocol, host, port
+}
+
+func defaultPort(protocol string) string {
+	if protocol == "https" {
+		return "443"
+	}
+
+	return "80"
+}
+
+func orDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
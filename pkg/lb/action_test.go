@@ -0,0 +1,231 @@
+package lb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightedForwardAction_Handle(t *testing.T) {
+	t.Run("Should split traffic between target groups by weight", func(t *testing.T) {
+		stable := &MockedAlgorithm{}
+		canary := &MockedAlgorithm{}
+
+		action := NewWeightedForwardAction([]WeightedTargetGroup{
+			{TargetGroup: newTestTargetGroup("stable", stable), Weight: 95},
+			{TargetGroup: newTestTargetGroup("canary", canary), Weight: 5},
+		}, 0)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+
+		stable.On("Handle", w, r).Return(nil)
+		canary.On("Handle", w, r).Return(nil)
+
+		action.randIntn = func(n int) int { return 94 }
+		assert.Nil(t, action.Handle(w, r))
+
+		action.randIntn = func(n int) int { return 95 }
+		assert.Nil(t, action.Handle(w, r))
+
+		stable.AssertNumberOfCalls(t, "Handle", 1)
+		canary.AssertNumberOfCalls(t, "Handle", 1)
+		assert.Empty(t, w.Result().Cookies())
+	})
+
+	t.Run("Should pin the client to its target group with a stickiness cookie", func(t *testing.T) {
+		stable := &MockedAlgorithm{}
+		canary := &MockedAlgorithm{}
+
+		action := NewWeightedForwardAction([]WeightedTargetGroup{
+			{TargetGroup: newTestTargetGroup("stable", stable), Weight: 50},
+			{TargetGroup: newTestTargetGroup("canary", canary), Weight: 50},
+		}, time.Hour)
+		action.randIntn = func(n int) int { return 99 }
+
+		first := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+
+		canary.On("Handle", first, r).Return(nil)
+
+		assert.Nil(t, action.Handle(first, r))
+
+		cookies := first.Result().Cookies()
+
+		assert.Len(t, cookies, 1)
+		assert.Equal(t, "canary", cookies[0].Value)
+		assert.Equal(t, 3600, cookies[0].MaxAge)
+
+		action.randIntn = func(n int) int { return 0 }
+
+		second := httptest.NewRecorder()
+		stickyRequest := httptest.NewRequest("GET", "http://localhost/", nil)
+		stickyRequest.AddCookie(cookies[0])
+
+		canary.On("Handle", second, stickyRequest).Return(nil)
+
+		assert.Nil(t, action.Handle(second, stickyRequest))
+
+		canary.AssertNumberOfCalls(t, "Handle", 2)
+		stable.AssertNotCalled(t, "Handle")
+	})
+
+	t.Run("Should ignore a stickiness cookie for a drained target group", func(t *testing.T) {
+		stable := &MockedAlgorithm{}
+		canary := &MockedAlgorithm{}
+
+		action := NewWeightedForwardAction([]WeightedTargetGroup{
+			{TargetGroup: newTestTargetGroup("stable", stable), Weight: 100},
+			{TargetGroup: newTestTargetGroup("canary", canary), Weight: 0},
+		}, time.Hour)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+		r.AddCookie(&http.Cookie{Name: targetGroupStickinessCookie, Value: "canary"})
+
+		stable.On("Handle", w, r).Return(nil)
+
+		assert.Nil(t, action.Handle(w, r))
+
+		stable.AssertExpectations(t)
+		canary.AssertNotCalled(t, "Handle")
+	})
+
+	t.Run("Should return an error when all weights are zero", func(t *testing.T) {
+		action := NewWeightedForwardAction([]WeightedTargetGroup{
+			{TargetGroup: newTestTargetGroup("stable", &MockedAlgorithm{}), Weight: 0},
+		}, 0)
+
+		err := action.Handle(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil))
+
+		assert.ErrorIs(t, err, errs.ErrNoTargetGroups)
+	})
+}
+
+func TestFixedResponseAction_Handle(t *testing.T) {
+	t.Run("Should respond with the configured status, content type and body", func(t *testing.T) {
+		action := NewFixedResponseAction(503, "text/html", "<h1>Under maintenance</h1>")
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+
+		assert.Nil(t, action.Handle(w, r))
+
+		assert.Equal(t, 503, w.Code)
+		assert.Equal(t, "text/html", w.Header().Get("Content-Type"))
+		assert.Equal(t, "<h1>Under maintenance</h1>", w.Body.String())
+	})
+}
+
+func TestRedirectAction_Handle(t *testing.T) {
+	t.Run("Should upgrade HTTP requests to HTTPS keeping host, path and query", func(t *testing.T) {
+		action := NewRedirectAction(NewRedirectActionParams{
+			StatusCode: http.StatusMovedPermanently,
+			Protocol:   "HTTPS",
+			Port:       "443",
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://example.com/orders?id=1", nil)
+
+		assert.Nil(t, action.Handle(w, r))
+
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "https://example.com/orders?id=1", w.Header().Get("Location"))
+	})
+
+	t.Run("Should keep a non default port of the original request", func(t *testing.T) {
+		action := NewRedirectAction(NewRedirectActionParams{
+			StatusCode: http.StatusFound,
+			Host:       "new.#{host}",
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://example.com:9000/", nil)
+
+		assert.Nil(t, action.Handle(w, r))
+
+		assert.Equal(t, "http://new.example.com:9000/", w.Header().Get("Location"))
+	})
+
+	t.Run("Should template the path of a legacy route", func(t *testing.T) {
+		action := NewRedirectAction(NewRedirectActionParams{
+			StatusCode: http.StatusPermanentRedirect,
+			Path:       "/v2#{path}",
+			Query:      "#{query}&from=legacy",
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "http://example.com/users?page=2", nil)
+
+		assert.Nil(t, action.Handle(w, r))
+
+		assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+		assert.Equal(t, "http://example.com/v2/users?page=2&from=legacy", w.Header().Get("Location"))
+	})
+
+	t.Run("Should drop the empty parts of a templated query", func(t *testing.T) {
+		action := NewRedirectAction(NewRedirectActionParams{
+			StatusCode: http.StatusFound,
+			Query:      "#{query}&from=legacy",
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://example.com/users", nil)
+
+		assert.Nil(t, action.Handle(w, r))
+
+		assert.Equal(t, "http://example.com/users?from=legacy", w.Header().Get("Location"))
+	})
+
+	t.Run("Should drop the default port when the protocol changes without a port", func(t *testing.T) {
+		action := NewRedirectAction(NewRedirectActionParams{
+			StatusCode: http.StatusMovedPermanently,
+			Protocol:   "HTTPS",
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://example.com:80/orders", nil)
+
+		assert.Nil(t, action.Handle(w, r))
+
+		assert.Equal(t, "https://example.com/orders", w.Header().Get("Location"))
+
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest("GET", "http://example.com:8080/orders", nil)
+
+		assert.Nil(t, action.Handle(w, r))
+
+		assert.Equal(t, "https://example.com:8080/orders", w.Header().Get("Location"))
+	})
+
+	t.Run("Should keep the brackets of an IPv6 host without a port", func(t *testing.T) {
+		action := NewRedirectAction(NewRedirectActionParams{
+			StatusCode: http.StatusFound,
+			Protocol:   "HTTPS",
+			Port:       "8443",
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://[::1]/orders", nil)
+
+		assert.Nil(t, action.Handle(w, r))
+
+		assert.Equal(t, "https://[::1]:8443/orders", w.Header().Get("Location"))
+
+		action = NewRedirectAction(NewRedirectActionParams{
+			StatusCode: http.StatusFound,
+			Protocol:   "HTTPS",
+		})
+
+		w = httptest.NewRecorder()
+
+		assert.Nil(t, action.Handle(w, r))
+
+		assert.Equal(t, "https://[::1]/orders", w.Header().Get("Location"))
+	})
+}
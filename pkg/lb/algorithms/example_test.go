@@ -0,0 +1,69 @@
+package lb_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	"github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+// firstHealthy sends every request to the first healthy target, falling
+// back to the next ones in order.
+type firstHealthy struct {
+	targets      []*targetgroup.Target
+	proxyFactory alg.ProxyFactory
+}
+
+func (f *firstHealthy) Handle(w http.ResponseWriter, req *http.Request) error {
+	for _, target := range f.targets {
+		if target.IsHealthy() {
+			f.proxyFactory.Create(target.Host, target.Port).ServeHTTP(w, req)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no healthy target")
+}
+
+func init() {
+	alg.Register("first-healthy", alg.NoOptions, func(targets []*targetgroup.Target, proxyFactory alg.ProxyFactory, _ struct{}) alg.Algorithm {
+		return &firstHealthy{targets: targets, proxyFactory: proxyFactory}
+	})
+}
+
+type printingProxy string
+
+func (p printingProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	fmt.Printf("%s %s to %s\n", req.Method, req.URL.Path, string(p))
+}
+
+type printingProxyFactory struct{}
+
+func (printingProxyFactory) Create(host string, port int) alg.Proxy {
+	return printingProxy(fmt.Sprintf("%s:%d", host, port))
+}
+
+// An algorithm registered from outside golb is built by name, like the
+// built-in ones named in the config file.
+func ExampleRegister() {
+	primary := targetgroup.NewTarget("primary", 8080)
+	backup := targetgroup.NewTarget("backup", 8080)
+	backup.SetHealthy(true)
+
+	algorithm, err := alg.New("first-healthy", []*targetgroup.Target{primary, backup}, printingProxyFactory{}, nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	algorithm.Handle(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders", nil))
+
+	primary.SetHealthy(true)
+	algorithm.Handle(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders", nil))
+
+	// Output:
+	// GET /orders to backup:8080
+	// GET /orders to primary:8080
+}
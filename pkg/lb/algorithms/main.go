@@ -1,9 +1,57 @@
 package lb
 
 import (
+	"net"
 	"net/http"
+
+	"github.com/joaosczip/go-lb/pkg/lb/targetgroup"
 )
 
-type Algorithm interface {
-	Handle(w http.ResponseWriter, r *http.Request) error
+type Algorithm = targetgroup.Algorithm
+
+// Proxy relays a request to the target it was created for.
+type Proxy interface {
+	ServeHTTP(w http.ResponseWriter, req *http.Request)
+}
+
+// ProxyFactory creates the proxies algorithms relay requests through, so
+// that they pick targets without knowing how targets are reached.
+type ProxyFactory interface {
+	Create(host string, port int) Proxy
+}
+
+// ConnProxy relays a client connection to the target at host:port until
+// either side closes it.
+type ConnProxy interface {
+	ServeConn(conn net.Conn, host string, port int) error
+}
+
+// ConnAlgorithm is the layer-4 counterpart of Algorithm: it picks a target
+// for a client connection and hands both to the proxy.
+type ConnAlgorithm interface {
+	HandleConn(conn net.Conn, proxy ConnProxy) error
+}
+
+// Wrapper is implemented by algorithms that delegate to other algorithms,
+// which then decide what the wrapper supports.
+type Wrapper interface {
+	Unwrap() []Algorithm
+}
+
+// SupportsConns reports whether algorithm handles connections, along with
+// every algorithm it wraps.
+func SupportsConns(algorithm Algorithm) bool {
+	if _, ok := algorithm.(ConnAlgorithm); !ok {
+		return false
+	}
+
+	if wrapper, ok := algorithm.(Wrapper); ok {
+		for _, wrapped := range wrapper.Unwrap() {
+			if !SupportsConns(wrapped) {
+				return false
+			}
+		}
+	}
+
+	return true
 }
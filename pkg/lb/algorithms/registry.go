@@ -0,0 +1,126 @@
+package lb
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+// Options are the options of an algorithm as written in the config file.
+type Options map[string]any
+
+// constructor decodes the options of an algorithm and builds it.
+type constructor func(targets []*targetgroup.Target, proxyFactory ProxyFactory, options Options) (Algorithm, error)
+
+type registration struct {
+	newAlgorithm constructor
+	pinsKeys     bool
+}
+
+var (
+	registry    = make(map[string]registration)
+	registryMux sync.RWMutex
+)
+
+// Register makes an algorithm available to target groups by name. decode
+// validates its options from the config file, which New then hands to
+// newAlgorithm. Like database/sql.Register, it panics when the name is taken.
+func Register[O any](name string, decode func(options Options) (O, error), newAlgorithm func(targets []*targetgroup.Target, proxyFactory ProxyFactory, opts O) Algorithm) {
+	register(name, decode, newAlgorithm, false)
+}
+
+// RegisterHashing registers an algorithm like Register, for algorithms that
+// pin request keys to targets. Their targets are never split into failover
+// tiers or zones, as drawing one of them at random would send a key to a
+// different target from one request to the next.
+func RegisterHashing[O any](name string, decode func(options Options) (O, error), newAlgorithm func(targets []*targetgroup.Target, proxyFactory ProxyFactory, opts O) Algorithm) {
+	register(name, decode, newAlgorithm, true)
+}
+
+func register[O any](name string, decode func(options Options) (O, error), newAlgorithm func(targets []*targetgroup.Target, proxyFactory ProxyFactory, opts O) Algorithm, pinsKeys bool) {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("algorithm %s is already registered", name))
+	}
+
+	registry[name] = registration{
+		newAlgorithm: func(targets []*targetgroup.Target, proxyFactory ProxyFactory, options Options) (Algorithm, error) {
+			opts, err := decode(options)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid options for algorithm %s: %v", name, err)
+			}
+
+			return newAlgorithm(targets, proxyFactory, opts), nil
+		},
+		pinsKeys: pinsKeys,
+	}
+}
+
+// PinsKeys reports whether the algorithm registered under name was
+// registered with RegisterHashing.
+func PinsKeys(name string) bool {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+
+	return registry[name].pinsKeys
+}
+
+// New builds the algorithm registered under name.
+func New(name string, targets []*targetgroup.Target, proxyFactory ProxyFactory, options Options) (Algorithm, error) {
+	registryMux.RLock()
+	registered, ok := registry[name]
+	registryMux.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown algorithm %q, expected one of %v", name, Names())
+	}
+
+	return registered.newAlgorithm(targets, proxyFactory, options)
+}
+
+// Names returns the registered algorithms in alphabetical order.
+func Names() []string {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+
+	names := make([]string, 0, len(registry))
+
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// NoOptions is the decoder of algorithms that take no options.
+func NoOptions(options Options) (struct{}, error) {
+	return struct{}{}, CheckOptions(options)
+}
+
+// CheckOptions returns an error naming the first option, alphabetically,
+// that is not one of known.
+func CheckOptions(options Options, known ...string) error {
+	var unknown []string
+
+	for name := range options {
+		if !slices.Contains(known, name) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+
+	return fmt.Errorf("unknown option %q", unknown[0])
+}
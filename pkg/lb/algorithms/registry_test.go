@@ -0,0 +1,85 @@
+package lb
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+type firstTarget struct {
+	target *targetgroup.Target
+	header string
+}
+
+func (f *firstTarget) Handle(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+type firstTargetOptions struct {
+	header string
+}
+
+func decodeFirstTargetOptions(options Options) (firstTargetOptions, error) {
+	if err := CheckOptions(options, "header"); err != nil {
+		return firstTargetOptions{}, err
+	}
+
+	header, _ := options["header"].(string)
+
+	return firstTargetOptions{header: header}, nil
+}
+
+func init() {
+	Register("first-target", decodeFirstTargetOptions, func(targets []*targetgroup.Target, proxyFactory ProxyFactory, opts firstTargetOptions) Algorithm {
+		return &firstTarget{target: targets[0], header: opts.header}
+	})
+	RegisterHashing("first-target-hashing", NoOptions, func(targets []*targetgroup.Target, proxyFactory ProxyFactory, _ struct{}) Algorithm {
+		return &firstTarget{target: targets[0]}
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	targets := []*targetgroup.Target{targetgroup.NewTarget("localhost", 8080)}
+
+	t.Run("Should build a registered algorithm with its decoded options", func(t *testing.T) {
+		algorithm, err := New("first-target", targets, nil, Options{"header": "X-User"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &firstTarget{target: targets[0], header: "X-User"}, algorithm)
+	})
+
+	t.Run("Should return an error for an unknown algorithm", func(t *testing.T) {
+		_, err := New("fastest", targets, nil, nil)
+
+		assert.EqualError(t, err, `unknown algorithm "fastest", expected one of [first-healthy first-target first-target-hashing]`)
+	})
+
+	t.Run("Should return an error naming the algorithm when its options are invalid", func(t *testing.T) {
+		_, err := New("first-target", targets, nil, Options{"heder": "X-User"})
+
+		assert.EqualError(t, err, `invalid options for algorithm first-target: unknown option "heder"`)
+	})
+
+	t.Run("Should panic when a name is registered twice", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Register("first-target", NoOptions, func(targets []*targetgroup.Target, proxyFactory ProxyFactory, _ struct{}) Algorithm {
+				return &firstTarget{}
+			})
+		})
+	})
+
+	t.Run("Should report the algorithms registered as pinning keys", func(t *testing.T) {
+		assert.True(t, PinsKeys("first-target-hashing"))
+		assert.False(t, PinsKeys("first-target"))
+		assert.False(t, PinsKeys("fastest"))
+	})
+
+	t.Run("Should reject any option of an algorithm without options", func(t *testing.T) {
+		_, err := NoOptions(Options{"decay": 10})
+
+		assert.EqualError(t, err, `unknown option "decay"`)
+	})
+}
@@ -0,0 +1,49 @@
+package lb
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	clientCertSubjectHeader = "X-Client-Cert-Subject"
+	clientCertSanHeader     = "X-Client-Cert-San"
+)
+
+// setClientCertificateHeaders forwards the subject and SANs of a verified
+// client certificate to the targets. Incoming values are always dropped so
+// clients cannot spoof them.
+func setClientCertificateHeaders(r *http.Request) {
+	r.Header.Del(clientCertSubjectHeader)
+	r.Header.Del(clientCertSanHeader)
+
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return
+	}
+
+	leaf := r.TLS.VerifiedChains[0][0]
+
+	var sans []string
+
+	for _, name := range leaf.DNSNames {
+		sans = append(sans, "DNS:"+name)
+	}
+
+	for _, email := range leaf.EmailAddresses {
+		sans = append(sans, "email:"+email)
+	}
+
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, "IP:"+ip.String())
+	}
+
+	for _, uri := range leaf.URIs {
+		sans = append(sans, "URI:"+uri.String())
+	}
+
+	r.Header.Set(clientCertSubjectHeader, leaf.Subject.String())
+
+	if len(sans) > 0 {
+		r.Header.Set(clientCertSanHeader, strings.Join(sans, ","))
+	}
+}
@@ -0,0 +1,43 @@
+package lb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetClientCertificateHeaders(t *testing.T) {
+	t.Run("Should forward the subject and SANs of a verified client certificate", func(t *testing.T) {
+		leaf := &x509.Certificate{
+			Subject:        pkix.Name{CommonName: "billing", Organization: []string{"Acme"}},
+			DNSNames:       []string{"billing.internal"},
+			EmailAddresses: []string{"ops@acme.com"},
+			IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		}
+
+		r := httptest.NewRequest("GET", "https://localhost/", nil)
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+
+		setClientCertificateHeaders(r)
+
+		assert.Equal(t, "CN=billing,O=Acme", r.Header.Get("X-Client-Cert-Subject"))
+		assert.Equal(t, "DNS:billing.internal,email:ops@acme.com,IP:10.0.0.1", r.Header.Get("X-Client-Cert-San"))
+	})
+
+	t.Run("Should drop spoofed headers when there is no verified certificate", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "https://localhost/", nil)
+		r.TLS = &tls.ConnectionState{}
+		r.Header.Set("X-Client-Cert-Subject", "CN=admin")
+		r.Header.Set("X-Client-Cert-San", "DNS:admin")
+
+		setClientCertificateHeaders(r)
+
+		assert.Empty(t, r.Header.Values("X-Client-Cert-Subject"))
+		assert.Empty(t, r.Header.Values("X-Client-Cert-San"))
+	})
+}
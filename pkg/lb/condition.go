@@ -0,0 +1,340 @@
+package lb
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/joaosczip/go-lb/internal/grpc"
+)
+
+type Condition interface {
+	Matches(r *http.Request) bool
+}
+
+type pathPatternCondition struct {
+	patterns []string
+}
+
+func NewPathPatternCondition(patterns []string) Condition {
+	return &pathPatternCondition{
+		patterns: patterns,
+	}
+}
+
+func (c *pathPatternCondition) Matches(r *http.Request) bool {
+	for _, pattern := range c.patterns {
+		if matchWildcard(pattern, r.URL.Path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type pathPrefixCondition struct {
+	prefixes []string
+}
+
+func NewPathPrefixCondition(prefixes []string) Condition {
+	return &pathPrefixCondition{
+		prefixes: prefixes,
+	}
+}
+
+func (c *pathPrefixCondition) Matches(r *http.Request) bool {
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// grpcMethodCondition matches gRPC calls whose "package.Service/Method" name
+// matches one of the patterns, e.g. "pkg.Service/*".
+type grpcMethodCondition struct {
+	patterns []string
+}
+
+func NewGrpcMethodCondition(patterns []string) Condition {
+	return &grpcMethodCondition{
+		patterns: patterns,
+	}
+}
+
+func (c *grpcMethodCondition) Matches(r *http.Request) bool {
+	if r.Method != http.MethodPost || !grpc.IsRequest(r) {
+		return false
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, "/")
+
+	for _, pattern := range c.patterns {
+		if matchWildcard(strings.TrimPrefix(pattern, "/"), method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type hostHeaderCondition struct {
+	patterns []string
+}
+
+func NewHostHeaderCondition(patterns []string) Condition {
+	lowered := make([]string, len(patterns))
+
+	for i, pattern := range patterns {
+		lowered[i] = strings.ToLower(pattern)
+	}
+
+	return &hostHeaderCondition{
+		patterns: lowered,
+	}
+}
+
+func (c *hostHeaderCondition) Matches(r *http.Request) bool {
+	host := r.Host
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.ToLower(host)
+
+	for _, pattern := range c.patterns {
+		if matchWildcard(pattern, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type httpMethodCondition struct {
+	methods []string
+}
+
+func NewHttpMethodCondition(methods []string) Condition {
+	uppered := make([]string, len(methods))
+
+	for i, method := range methods {
+		uppered[i] = strings.ToUpper(method)
+	}
+
+	return &httpMethodCondition{
+		methods: uppered,
+	}
+}
+
+func (c *httpMethodCondition) Matches(r *http.Request) bool {
+	for _, method := range c.methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+type httpHeaderCondition struct {
+	name   string
+	values []string
+}
+
+func NewHttpHeaderCondition(name string, values []string) Condition {
+	return &httpHeaderCondition{
+		name:   name,
+		values: values,
+	}
+}
+
+func (c *httpHeaderCondition) Matches(r *http.Request) bool {
+	for _, headerValue := range r.Header.Values(c.name) {
+		for _, value := range c.values {
+			if strings.EqualFold(headerValue, value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type httpHeaderRegexCondition struct {
+	name     string
+	patterns []*regexp.Regexp
+}
+
+func NewHttpHeaderRegexCondition(name string, patterns []*regexp.Regexp) Condition {
+	return &httpHeaderRegexCondition{
+		name:     name,
+		patterns: patterns,
+	}
+}
+
+func (c *httpHeaderRegexCondition) Matches(r *http.Request) bool {
+	for _, headerValue := range r.Header.Values(c.name) {
+		for _, pattern := range c.patterns {
+			if pattern.MatchString(headerValue) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type queryStringCondition struct {
+	key    string
+	values []string
+}
+
+// NewQueryStringCondition matches when the query parameter key carries one of
+// the given values. With no values, the presence of the key is enough.
+func NewQueryStringCondition(key string, values []string) Condition {
+	return &queryStringCondition{
+		key:    key,
+		values: values,
+	}
+}
+
+func (c *queryStringCondition) Matches(r *http.Request) bool {
+	queryValues, ok := r.URL.Query()[c.key]
+
+	if !ok {
+		return false
+	}
+
+	if len(c.values) == 0 {
+		return true
+	}
+
+	for _, queryValue := range queryValues {
+		for _, value := range c.values {
+			if matchWildcard(value, queryValue) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type sourceIpCondition struct {
+	prefixes []netip.Prefix
+}
+
+func NewSourceIpCondition(prefixes []netip.Prefix) Condition {
+	return &sourceIpCondition{
+		prefixes: prefixes,
+	}
+}
+
+func (c *sourceIpCondition) Matches(r *http.Request) bool {
+	addr, ok := clientAddr(r)
+
+	if !ok {
+		return false
+	}
+
+	for _, prefix := range c.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type anyCondition struct {
+	conditions []Condition
+}
+
+func NewAnyCondition(conditions []Condition) Condition {
+	return &anyCondition{
+		conditions: conditions,
+	}
+}
+
+func (c *anyCondition) Matches(r *http.Request) bool {
+	for _, condition := range c.conditions {
+		if condition.Matches(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type allCondition struct {
+	conditions []Condition
+}
+
+func NewAllCondition(conditions []Condition) Condition {
+	return &allCondition{
+		conditions: conditions,
+	}
+}
+
+func (c *allCondition) Matches(r *http.Request) bool {
+	for _, condition := range c.conditions {
+		if !condition.Matches(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func clientAddr(r *http.Request) (netip.Addr, bool) {
+	host := r.RemoteAddr
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	addr, err := netip.ParseAddr(host)
+
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+// matchWildcard reports whether s matches pattern, where '*' matches any
+// sequence of characters (including '/') and '?' matches a single one.
+func matchWildcard(pattern, s string) bool {
+	p, i := 0, 0
+	starIdx, match := -1, 0
+
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			starIdx = p
+			match = i
+			p++
+		case starIdx != -1:
+			p = starIdx + 1
+			match++
+			i = match
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
@@ -0,0 +1,100 @@
+package lb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditions(t *testing.T) {
+	t.Run("Should match a header value regardless of case", func(t *testing.T) {
+		condition := NewHttpHeaderCondition("X-Beta", []string{"1", "true"})
+
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+		r.Header.Set("x-beta", "TRUE")
+
+		assert.True(t, condition.Matches(r))
+
+		r.Header.Set("x-beta", "0")
+
+		assert.False(t, condition.Matches(r))
+	})
+
+	t.Run("Should match a header against a regex", func(t *testing.T) {
+		condition := NewHttpHeaderRegexCondition("User-Agent", []*regexp.Regexp{regexp.MustCompile(`^Mobile/\d+$`)})
+
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+		r.Header.Set("User-Agent", "Mobile/42")
+
+		assert.True(t, condition.Matches(r))
+
+		r.Header.Set("User-Agent", "Desktop/42")
+
+		assert.False(t, condition.Matches(r))
+	})
+
+	t.Run("Should match gRPC calls by service and method", func(t *testing.T) {
+		condition := NewGrpcMethodCondition([]string{"orders.v1.Orders/*", "/users.v1.Users/Get"})
+
+		grpcRequest := func(path string) *http.Request {
+			r := httptest.NewRequest("POST", "http://localhost"+path, nil)
+			r.Header.Set("Content-Type", "application/grpc")
+			return r
+		}
+
+		assert.True(t, condition.Matches(grpcRequest("/orders.v1.Orders/Create")))
+		assert.True(t, condition.Matches(grpcRequest("/users.v1.Users/Get")))
+		assert.False(t, condition.Matches(grpcRequest("/users.v1.Users/Delete")))
+		assert.False(t, condition.Matches(httptest.NewRequest("POST", "http://localhost/orders.v1.Orders/Create", nil)))
+	})
+
+	t.Run("Should match a query parameter value", func(t *testing.T) {
+		condition := NewQueryStringCondition("tenant", []string{"acme-*"})
+
+		assert.True(t, condition.Matches(httptest.NewRequest("GET", "http://localhost/?tenant=acme-eu", nil)))
+		assert.False(t, condition.Matches(httptest.NewRequest("GET", "http://localhost/?tenant=globex", nil)))
+		assert.False(t, condition.Matches(httptest.NewRequest("GET", "http://localhost/", nil)))
+	})
+
+	t.Run("Should match the presence of a query parameter when no values are given", func(t *testing.T) {
+		condition := NewQueryStringCondition("debug", nil)
+
+		assert.True(t, condition.Matches(httptest.NewRequest("GET", "http://localhost/?debug", nil)))
+		assert.False(t, condition.Matches(httptest.NewRequest("GET", "http://localhost/?other=1", nil)))
+	})
+
+	t.Run("Should match the source ip against a CIDR", func(t *testing.T) {
+		condition := NewSourceIpCondition([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+		r.RemoteAddr = "10.1.2.3:51234"
+
+		assert.True(t, condition.Matches(r))
+
+		r.RemoteAddr = "192.168.0.1:51234"
+
+		assert.False(t, condition.Matches(r))
+	})
+
+	t.Run("Should combine conditions with AND and OR", func(t *testing.T) {
+		condition := NewAllCondition([]Condition{
+			NewPathPrefixCondition([]string{"/app"}),
+			NewAnyCondition([]Condition{
+				NewHttpHeaderCondition("X-Beta", []string{"1"}),
+				NewQueryStringCondition("tenant", []string{"beta"}),
+			}),
+		})
+
+		header := httptest.NewRequest("GET", "http://localhost/app", nil)
+		header.Header.Set("X-Beta", "1")
+
+		assert.True(t, condition.Matches(header))
+		assert.True(t, condition.Matches(httptest.NewRequest("GET", "http://localhost/app?tenant=beta", nil)))
+		assert.False(t, condition.Matches(httptest.NewRequest("GET", "http://localhost/app", nil)))
+		assert.False(t, condition.Matches(httptest.NewRequest("GET", "http://localhost/other?tenant=beta", nil)))
+	})
+}
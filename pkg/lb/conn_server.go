@@ -0,0 +1,142 @@
+package lb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	tg "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+// connServer accepts raw TCP connections and hands each one to the
+// algorithm of its target group.
+type connServer struct {
+	targetGroup *tg.TargetGroup
+	proxy       alg.ConnProxy
+	mux         sync.Mutex
+	listener    net.Listener
+	conns       map[net.Conn]struct{}
+	closed      bool
+	wg          sync.WaitGroup
+}
+
+func newConnServer(targetGroup *tg.TargetGroup, proxy alg.ConnProxy) *connServer {
+	return &connServer{
+		targetGroup: targetGroup,
+		proxy:       proxy,
+		conns:       make(map[net.Conn]struct{}),
+	}
+}
+
+func (s *connServer) Serve(ln net.Listener) error {
+	algorithm, ok := s.targetGroup.Algorithm.(alg.ConnAlgorithm)
+
+	if !ok {
+		return fmt.Errorf("the algorithm of target group %s does not support connections", s.targetGroup.Name)
+	}
+
+	s.mux.Lock()
+
+	if s.closed {
+		s.mux.Unlock()
+		return net.ErrClosed
+	}
+
+	s.listener = ln
+	s.mux.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+
+		if err != nil {
+			if s.isClosed() {
+				return net.ErrClosed
+			}
+
+			return err
+		}
+
+		if !s.track(conn) {
+			conn.Close()
+			return net.ErrClosed
+		}
+
+		go s.serveConn(algorithm, conn)
+	}
+}
+
+func (s *connServer) serveConn(algorithm alg.ConnAlgorithm, conn net.Conn) {
+	defer s.untrack(conn)
+	defer conn.Close()
+
+	if err := algorithm.HandleConn(conn, s.proxy); err != nil {
+		log.Printf("could not proxy connection from %s: %v", conn.RemoteAddr(), err)
+	}
+}
+
+func (s *connServer) track(conn net.Conn) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+
+	return true
+}
+
+func (s *connServer) untrack(conn net.Conn) {
+	s.mux.Lock()
+	delete(s.conns, conn)
+	s.mux.Unlock()
+
+	s.wg.Done()
+}
+
+func (s *connServer) isClosed() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.closed
+}
+
+// Shutdown stops accepting connections and waits for the open ones to
+// finish. Connections still open when ctx is done are closed.
+func (s *connServer) Shutdown(ctx context.Context) error {
+	s.mux.Lock()
+	s.closed = true
+
+	if s.listener != nil {
+		s.listener.Close()
+	}
+
+	s.mux.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mux.Lock()
+
+		for conn := range s.conns {
+			conn.Close()
+		}
+
+		s.mux.Unlock()
+
+		return ctx.Err()
+	}
+}
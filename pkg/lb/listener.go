@@ -0,0 +1,184 @@
+package lb
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/grpc"
+	"github.com/joaosczip/go-lb/internal/proxyproto"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	tg "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+const proxyProtocolHeaderTimeout = 5 * time.Second
+
+// Listener accepts client traffic on a port. HTTP and HTTPS listeners route
+// each request through their rules, while TCP and UDP listeners relay every
+// connection or flow to a target of their target group.
+type Listener struct {
+	Port          int
+	Protocol      string
+	TLSConfig     *tls.Config
+	Rules         *RuleTable
+	TargetGroup   *tg.TargetGroup
+	ProxyProtocol bool
+	H2C           bool
+	server        *http.Server
+	connServer    *connServer
+	packetServer  *packetServer
+}
+
+type NewListenerParams struct {
+	Port          int
+	Protocol      string
+	TLSConfig     *tls.Config
+	Rules         *RuleTable
+	TargetGroup   *tg.TargetGroup
+	ConnProxy     alg.ConnProxy
+	ProxyProtocol bool
+	H2C           bool
+}
+
+func NewListener(params NewListenerParams) *Listener {
+	listener := &Listener{
+		Port:          params.Port,
+		Protocol:      params.Protocol,
+		TLSConfig:     params.TLSConfig,
+		Rules:         params.Rules,
+		TargetGroup:   params.TargetGroup,
+		ProxyProtocol: params.ProxyProtocol,
+		H2C:           params.H2C,
+	}
+
+	switch params.Protocol {
+	case "tcp":
+		listener.connServer = newConnServer(params.TargetGroup, params.ConnProxy)
+		return listener
+	case "udp":
+		listener.packetServer = newPacketServer(params.TargetGroup, params.ConnProxy)
+		return listener
+	}
+
+	// HTTPS listeners negotiate HTTP/2 through ALPN, while plaintext ones only
+	// speak it to clients that use h2c with prior knowledge, when enabled.
+	var protocols http.Protocols
+
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(params.Protocol == "https")
+	protocols.SetUnencryptedHTTP2(params.Protocol == "http" && params.H2C)
+
+	listener.server = &http.Server{
+		Addr:      fmt.Sprintf(":%d", params.Port),
+		Handler:   listener,
+		TLSConfig: params.TLSConfig,
+		Protocols: &protocols,
+	}
+
+	return listener
+}
+
+func (l *Listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	setClientCertificateHeaders(r)
+
+	action := l.Rules.Match(r)
+
+	if action == nil {
+		if grpc.IsRequest(r) {
+			grpc.WriteError(w, grpc.Unimplemented, "no rule matched the request")
+			return
+		}
+
+		http.Error(w, "no rule matched the request", http.StatusNotFound)
+		return
+	}
+
+	err := action.Handle(w, r)
+
+	if err != nil {
+		// gRPC clients expect a grpc-status rather than an HTTP error, and
+		// UNAVAILABLE tells them the call may be retried.
+		if grpc.IsRequest(r) {
+			grpc.WriteError(w, grpc.Unavailable, err.Error())
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	}
+}
+
+func (l *Listener) ListenAndServe() error {
+	if l.packetServer != nil {
+		pc, err := net.ListenPacket("udp", fmt.Sprintf(":%d", l.Port))
+
+		if err != nil {
+			return err
+		}
+
+		return l.ServePacket(pc)
+	}
+
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", l.Port))
+
+	if err != nil {
+		return err
+	}
+
+	return l.Serve(ln)
+}
+
+// Serve accepts connections on ln, which lets callers pick the socket (an
+// ephemeral port in tests, for instance) instead of binding Port.
+func (l *Listener) Serve(ln net.Listener) error {
+	if l.packetServer != nil {
+		return fmt.Errorf("listener %d uses protocol udp, use ServePacket instead", l.Port)
+	}
+
+	if l.ProxyProtocol {
+		ln = proxyproto.NewListener(ln, proxyProtocolHeaderTimeout)
+	}
+
+	if l.connServer != nil {
+		return l.connServer.Serve(ln)
+	}
+
+	if l.Protocol == "https" {
+		return l.server.ServeTLS(ln, "", "")
+	}
+
+	return l.server.Serve(ln)
+}
+
+// ServePacket reads the datagrams of a UDP listener from pc.
+func (l *Listener) ServePacket(pc net.PacketConn) error {
+	if l.packetServer == nil {
+		return fmt.Errorf("listener %d does not use protocol udp", l.Port)
+	}
+
+	return l.packetServer.Serve(pc)
+}
+
+// FlowStats reports the flow table of a UDP listener. Other listeners have no
+// flows.
+func (l *Listener) FlowStats() FlowStats {
+	if l.packetServer == nil {
+		return FlowStats{}
+	}
+
+	return l.packetServer.Stats()
+}
+
+func (l *Listener) Shutdown(ctx context.Context) error {
+	if l.connServer != nil {
+		return l.connServer.Shutdown(ctx)
+	}
+
+	if l.packetServer != nil {
+		return l.packetServer.Shutdown(ctx)
+	}
+
+	return l.server.Shutdown(ctx)
+}
@@ -0,0 +1,160 @@
+package lb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	errs "github.com/joaosczip/go-lb/internal/errors"
+	tg "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListener_ServeHTTP(t *testing.T) {
+	t.Run("Should forward the request to exactly one target group", func(t *testing.T) {
+		first := &MockedAlgorithm{}
+		second := &MockedAlgorithm{}
+
+		rules := NewRuleTable([]*Rule{
+			NewRule(1, []Condition{NewPathPrefixCondition([]string{"/second"})}, NewForwardAction(newTestTargetGroup("second", second))),
+		}, NewForwardAction(newTestTargetGroup("first", first)))
+
+		listener := NewListener(NewListenerParams{Port: 9000, Protocol: "http", Rules: rules})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost/second/path", nil)
+
+		second.On("Handle", w, r).Return(nil)
+
+		listener.ServeHTTP(w, r)
+
+		second.AssertExpectations(t)
+		first.AssertNotCalled(t, "Handle")
+	})
+
+	t.Run("Should respond with service unavailable when the action fails", func(t *testing.T) {
+		algorithm := &MockedAlgorithm{}
+		rules := NewRuleTable(nil, NewForwardAction(newTestTargetGroup("first", algorithm)))
+
+		listener := NewListener(NewListenerParams{Port: 9000, Protocol: "http", Rules: rules})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+
+		algorithm.On("Handle", w, r).Return(errors.New("no healthy targets available"))
+
+		listener.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+
+	t.Run("Should respond to gRPC calls with UNAVAILABLE when there are no healthy targets", func(t *testing.T) {
+		algorithm := &MockedAlgorithm{}
+		rules := NewRuleTable(nil, NewForwardAction(newTestTargetGroup("first", algorithm)))
+
+		listener := NewListener(NewListenerParams{Port: 9000, Protocol: "http", Rules: rules})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "http://localhost/orders.v1.Orders/Create", nil)
+		r.Header.Set("Content-Type", "application/grpc")
+
+		algorithm.On("Handle", w, r).Return(errs.ErrNoHealthyTargets)
+
+		listener.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/grpc", w.Header().Get("Content-Type"))
+		assert.Equal(t, "14", w.Header().Get("Grpc-Status"))
+		assert.Equal(t, "no healthy targets available", w.Header().Get("Grpc-Message"))
+	})
+
+	t.Run("Should respond with not found when there is no default action", func(t *testing.T) {
+		listener := NewListener(NewListenerParams{Port: 9000, Protocol: "http", Rules: NewRuleTable(nil, nil)})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+
+		listener.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func serveOnEphemeralPort(t *testing.T, listener *Listener) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go listener.Serve(ln)
+
+	return "http://" + ln.Addr().String()
+}
+
+func getBody(t *testing.T, url string) string {
+	res, err := http.Get(url)
+	assert.NoError(t, err)
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+
+	return string(body)
+}
+
+func TestLoadBalancer_Listeners(t *testing.T) {
+	t.Run("Should run several load balancers with their own listeners in one process", func(t *testing.T) {
+		apps := NewListener(NewListenerParams{
+			Protocol: "http",
+			Rules:    NewRuleTable(nil, NewFixedResponseAction(200, "text/plain", "apps")),
+		})
+		internal := NewListener(NewListenerParams{
+			Protocol: "http",
+			Rules:    NewRuleTable(nil, NewFixedResponseAction(200, "text/plain", "internal")),
+		})
+
+		first := NewLoadBalancer([]*tg.TargetGroup{}, []*Listener{apps})
+		second := NewLoadBalancer([]*tg.TargetGroup{}, []*Listener{internal})
+
+		appsUrl := serveOnEphemeralPort(t, apps)
+		internalUrl := serveOnEphemeralPort(t, internal)
+
+		assert.Equal(t, "apps", getBody(t, appsUrl))
+		assert.Equal(t, "internal", getBody(t, internalUrl))
+
+		assert.NoError(t, first.Shutdown(context.Background()))
+		assert.NoError(t, second.Shutdown(context.Background()))
+	})
+
+	t.Run("Should accept h2c from clients with prior knowledge when enabled", func(t *testing.T) {
+		listener := NewListener(NewListenerParams{
+			Protocol: "http",
+			H2C:      true,
+			Rules:    NewRuleTable(nil, NewFixedResponseAction(200, "text/plain", "h2c")),
+		})
+
+		url := serveOnEphemeralPort(t, listener)
+
+		var protocols http.Protocols
+		protocols.SetUnencryptedHTTP2(true)
+
+		client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+
+		res, err := client.Get(url)
+		assert.NoError(t, err)
+
+		defer res.Body.Close()
+
+		assert.Equal(t, 2, res.ProtoMajor)
+		assert.NoError(t, listener.Shutdown(context.Background()))
+	})
+
+	t.Run("Should return an error when there are no listeners", func(t *testing.T) {
+		loadBalancer := NewLoadBalancer(nil, nil)
+
+		assert.EqualError(t, loadBalancer.ListenAndServe(), "no listeners configured")
+	})
+}
@@ -1,7 +1,9 @@
 package lb
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"net"
 	"net/http"
 
 	tg "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
@@ -9,25 +11,48 @@ import (
 
 type LoadBalancer struct {
 	TargetGroups []*tg.TargetGroup
-	Port int
+	Listeners    []*Listener
 }
 
-func NewLoadBalancer(targetGroups []*tg.TargetGroup, port int) *LoadBalancer {
+func NewLoadBalancer(targetGroups []*tg.TargetGroup, listeners []*Listener) *LoadBalancer {
 	return &LoadBalancer{
 		TargetGroups: targetGroups,
-		Port: port,
+		Listeners:    listeners,
 	}
 }
 
+// ListenAndServe starts every listener and blocks until one of them stops,
+// returning its error. Listeners closed through Shutdown are not reported.
 func (lb *LoadBalancer) ListenAndServe() error {
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		for _, targetGroup := range lb.TargetGroups {
-			err := targetGroup.Algorithm.Handle(w, r)
+	if len(lb.Listeners) == 0 {
+		return errors.New("no listeners configured")
+	}
+
+	errCh := make(chan error, len(lb.Listeners))
+
+	for _, listener := range lb.Listeners {
+		go func(listener *Listener) {
+			errCh <- listener.ListenAndServe()
+		}(listener)
+	}
+
+	err := <-errCh
+
+	if errors.Is(err, http.ErrServerClosed) || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+
+	return err
+}
 
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusServiceUnavailable)
-			}
+func (lb *LoadBalancer) Shutdown(ctx context.Context) error {
+	var shutdownErrs []error
+
+	for _, listener := range lb.Listeners {
+		if err := listener.Shutdown(ctx); err != nil {
+			shutdownErrs = append(shutdownErrs, err)
 		}
-	})
-	return http.ListenAndServe(fmt.Sprintf(":%d", lb.Port), nil)
+	}
+
+	return errors.Join(shutdownErrs...)
 }
@@ -0,0 +1,290 @@
+package lb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/proxy"
+	alg "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+	tg "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+)
+
+const flowQueueSize = 64
+
+type FlowStats struct {
+	ActiveFlows      int
+	TotalFlows       int64
+	DroppedDatagrams int64
+}
+
+// packetServer load balances UDP datagrams. Every client address gets a flow
+// that is pinned to one target until it goes idle, so replies route back to
+// the right client.
+type packetServer struct {
+	targetGroup      *tg.TargetGroup
+	proxy            alg.ConnProxy
+	mux              sync.Mutex
+	packetConn       net.PacketConn
+	flows            map[string]*flowConn
+	closed           bool
+	totalFlows       atomic.Int64
+	droppedDatagrams atomic.Int64
+	wg               sync.WaitGroup
+}
+
+func newPacketServer(targetGroup *tg.TargetGroup, proxy alg.ConnProxy) *packetServer {
+	return &packetServer{
+		targetGroup: targetGroup,
+		proxy:       proxy,
+		flows:       make(map[string]*flowConn),
+	}
+}
+
+func (s *packetServer) Serve(pc net.PacketConn) error {
+	algorithm, ok := s.targetGroup.Algorithm.(alg.ConnAlgorithm)
+
+	if !ok {
+		return fmt.Errorf("the algorithm of target group %s does not support connections", s.targetGroup.Name)
+	}
+
+	s.mux.Lock()
+
+	if s.closed {
+		s.mux.Unlock()
+		return net.ErrClosed
+	}
+
+	s.packetConn = pc
+	s.mux.Unlock()
+
+	buf := make([]byte, proxy.MaxDatagramSize)
+
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+
+		if err != nil {
+			if s.isClosed() {
+				return net.ErrClosed
+			}
+
+			return err
+		}
+
+		datagram := make([]byte, n)
+		copy(datagram, buf[:n])
+
+		flow, created := s.flowFor(pc, addr)
+
+		if flow == nil {
+			return net.ErrClosed
+		}
+
+		if created {
+			go s.serveFlow(algorithm, flow)
+		}
+
+		if !flow.deliver(datagram) {
+			s.droppedDatagrams.Add(1)
+		}
+	}
+}
+
+func (s *packetServer) flowFor(pc net.PacketConn, addr net.Addr) (*flowConn, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		return nil, false
+	}
+
+	if flow, ok := s.flows[addr.String()]; ok {
+		return flow, false
+	}
+
+	flow := newFlowConn(pc, addr)
+	s.flows[addr.String()] = flow
+	s.totalFlows.Add(1)
+	s.wg.Add(1)
+
+	return flow, true
+}
+
+func (s *packetServer) serveFlow(algorithm alg.ConnAlgorithm, flow *flowConn) {
+	defer s.wg.Done()
+	defer s.removeFlow(flow)
+	defer flow.Close()
+
+	if err := algorithm.HandleConn(flow, s.proxy); err != nil {
+		log.Printf("could not proxy datagrams from %s: %v", flow.RemoteAddr(), err)
+	}
+}
+
+func (s *packetServer) removeFlow(flow *flowConn) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.flows[flow.RemoteAddr().String()] == flow {
+		delete(s.flows, flow.RemoteAddr().String())
+	}
+}
+
+func (s *packetServer) isClosed() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.closed
+}
+
+func (s *packetServer) Stats() FlowStats {
+	s.mux.Lock()
+	activeFlows := len(s.flows)
+	s.mux.Unlock()
+
+	return FlowStats{
+		ActiveFlows:      activeFlows,
+		TotalFlows:       s.totalFlows.Load(),
+		DroppedDatagrams: s.droppedDatagrams.Load(),
+	}
+}
+
+// Shutdown stops reading datagrams and closes every flow.
+func (s *packetServer) Shutdown(ctx context.Context) error {
+	s.mux.Lock()
+	s.closed = true
+
+	if s.packetConn != nil {
+		s.packetConn.Close()
+	}
+
+	for _, flow := range s.flows {
+		flow.Close()
+	}
+
+	s.mux.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flowConn presents the datagrams of one client as a net.Conn: reads return
+// the datagrams received from the client, one per call, and writes send a
+// datagram back to it.
+type flowConn struct {
+	packetConn   net.PacketConn
+	remoteAddr   net.Addr
+	datagrams    chan []byte
+	closed       chan struct{}
+	closeOnce    sync.Once
+	mux          sync.Mutex
+	readDeadline time.Time
+}
+
+func newFlowConn(pc net.PacketConn, remoteAddr net.Addr) *flowConn {
+	return &flowConn{
+		packetConn: pc,
+		remoteAddr: remoteAddr,
+		datagrams:  make(chan []byte, flowQueueSize),
+		closed:     make(chan struct{}),
+	}
+}
+
+func (f *flowConn) deliver(datagram []byte) bool {
+	select {
+	case <-f.closed:
+		return false
+	default:
+	}
+
+	select {
+	case f.datagrams <- datagram:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *flowConn) Read(b []byte) (int, error) {
+	f.mux.Lock()
+	deadline := f.readDeadline
+	f.mux.Unlock()
+
+	var timeout <-chan time.Time
+
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	select {
+	case datagram := <-f.datagrams:
+		return copy(b, datagram), nil
+	case <-f.closed:
+		return 0, net.ErrClosed
+	case <-timeout:
+		return 0, os.ErrDeadlineExceeded
+	}
+}
+
+func (f *flowConn) Write(b []byte) (int, error) {
+	select {
+	case <-f.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+
+	return f.packetConn.WriteTo(b, f.remoteAddr)
+}
+
+func (f *flowConn) Close() error {
+	f.closeOnce.Do(func() {
+		close(f.closed)
+	})
+
+	return nil
+}
+
+func (f *flowConn) LocalAddr() net.Addr {
+	return f.packetConn.LocalAddr()
+}
+
+func (f *flowConn) RemoteAddr() net.Addr {
+	return f.remoteAddr
+}
+
+func (f *flowConn) SetDeadline(t time.Time) error {
+	return f.SetReadDeadline(t)
+}
+
+func (f *flowConn) SetReadDeadline(t time.Time) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.readDeadline = t
+
+	return nil
+}
+
+// SetWriteDeadline is a no-op: writes go straight to the shared socket and
+// never block on the client.
+func (f *flowConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,133 @@
+package lb
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joaosczip/go-lb/internal/algorithms"
+	"github.com/joaosczip/go-lb/internal/proxy"
+	tg "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+	"github.com/stretchr/testify/assert"
+)
+
+func startUdpEchoTarget(t *testing.T) *net.UDPAddr {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, proxy.MaxDatagramSize)
+
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+
+			if err != nil {
+				return
+			}
+
+			pc.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	return pc.LocalAddr().(*net.UDPAddr)
+}
+
+func exchangeDatagram(t *testing.T, client net.Conn, payload string) string {
+	_, err := client.Write([]byte(payload))
+	assert.NoError(t, err)
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	buf := make([]byte, proxy.MaxDatagramSize)
+	n, err := client.Read(buf)
+	assert.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+func TestListener_ServePacket(t *testing.T) {
+	t.Run("Should route replies back to each client and expire idle flows", func(t *testing.T) {
+		targetAddr := startUdpEchoTarget(t)
+
+		targets := []*tg.Target{{Host: "127.0.0.1", Port: targetAddr.Port}}
+		targets[0].SetHealthy(true)
+		targetGroup := &tg.TargetGroup{
+			Name:      "dns",
+			Protocol:  "udp",
+			Targets:   targets,
+			Algorithm: algorithms.NewRoundRobin(targets, nil),
+		}
+
+		listener := NewListener(NewListenerParams{
+			Protocol:    "udp",
+			TargetGroup: targetGroup,
+			ConnProxy:   proxy.NewUDPProxy(100 * time.Millisecond),
+		})
+
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		go listener.ServePacket(pc)
+
+		defer listener.Shutdown(context.Background())
+
+		first, err := net.Dial("udp", pc.LocalAddr().String())
+		assert.NoError(t, err)
+		defer first.Close()
+
+		second, err := net.Dial("udp", pc.LocalAddr().String())
+		assert.NoError(t, err)
+		defer second.Close()
+
+		assert.Equal(t, "query-1", exchangeDatagram(t, first, "query-1"))
+		assert.Equal(t, "query-2", exchangeDatagram(t, second, "query-2"))
+		assert.Equal(t, "query-3", exchangeDatagram(t, first, "query-3"))
+
+		stats := listener.FlowStats()
+
+		assert.Equal(t, 2, stats.ActiveFlows)
+		assert.Equal(t, int64(2), stats.TotalFlows)
+
+		assert.Eventually(t, func() bool {
+			return listener.FlowStats().ActiveFlows == 0
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+
+	t.Run("Should relay datagrams larger than a stream buffer whole", func(t *testing.T) {
+		targetAddr := startUdpEchoTarget(t)
+
+		targets := []*tg.Target{{Host: "127.0.0.1", Port: targetAddr.Port}}
+		targets[0].SetHealthy(true)
+		targetGroup := &tg.TargetGroup{
+			Name:      "syslog",
+			Protocol:  "udp",
+			Targets:   targets,
+			Algorithm: algorithms.NewRoundRobin(targets, nil),
+		}
+
+		listener := NewListener(NewListenerParams{
+			Protocol:    "udp",
+			TargetGroup: targetGroup,
+			ConnProxy:   proxy.NewUDPProxy(time.Second),
+		})
+
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		go listener.ServePacket(pc)
+
+		defer listener.Shutdown(context.Background())
+
+		client, err := net.Dial("udp", pc.LocalAddr().String())
+		assert.NoError(t, err)
+		defer client.Close()
+
+		payload := strings.Repeat("x", 40*1024)
+
+		assert.Equal(t, payload, exchangeDatagram(t, client, payload))
+	})
+}
@@ -0,0 +1,61 @@
+package lb
+
+import (
+	"net/http"
+	"sort"
+)
+
+type Rule struct {
+	Priority   int
+	Conditions []Condition
+	Action     Action
+}
+
+func NewRule(priority int, conditions []Condition, action Action) *Rule {
+	return &Rule{
+		Priority:   priority,
+		Conditions: conditions,
+		Action:     action,
+	}
+}
+
+func (r *Rule) Matches(req *http.Request) bool {
+	for _, condition := range r.Conditions {
+		if !condition.Matches(req) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type RuleTable struct {
+	Rules         []*Rule
+	DefaultAction Action
+}
+
+// NewRuleTable orders the rules by ascending priority, so the rule with the
+// lowest priority value is evaluated first.
+func NewRuleTable(rules []*Rule, defaultAction Action) *RuleTable {
+	sorted := make([]*Rule, len(rules))
+	copy(sorted, rules)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	return &RuleTable{
+		Rules:         sorted,
+		DefaultAction: defaultAction,
+	}
+}
+
+func (t *RuleTable) Match(req *http.Request) Action {
+	for _, rule := range t.Rules {
+		if rule.Matches(req) {
+			return rule.Action
+		}
+	}
+
+	return t.DefaultAction
+}
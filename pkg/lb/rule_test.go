@@ -0,0 +1,71 @@
+package lb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tg "github.com/joaosczip/go-lb/pkg/lb/targetgroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedAlgorithm struct {
+	mock.Mock
+}
+
+func (m *MockedAlgorithm) Handle(w http.ResponseWriter, r *http.Request) error {
+	args := m.Called(w, r)
+	return args.Error(0)
+}
+
+func newTestTargetGroup(name string, algorithm *MockedAlgorithm) *tg.TargetGroup {
+	return &tg.TargetGroup{
+		Name:      name,
+		Algorithm: algorithm,
+	}
+}
+
+func TestRuleTable_Match(t *testing.T) {
+	api := NewForwardAction(newTestTargetGroup("api", nil))
+	static := NewForwardAction(newTestTargetGroup("static", nil))
+	fallback := NewForwardAction(newTestTargetGroup("fallback", nil))
+
+	rules := NewRuleTable([]*Rule{
+		NewRule(20, []Condition{NewPathPrefixCondition([]string{"/static"})}, static),
+		NewRule(10, []Condition{
+			NewPathPatternCondition([]string{"/api/*"}),
+			NewHostHeaderCondition([]string{"*.Example.com"}),
+			NewHttpMethodCondition([]string{"get", "post"}),
+		}, api),
+	}, fallback)
+
+	t.Run("Should evaluate rules by ascending priority", func(t *testing.T) {
+		assert.Equal(t, 10, rules.Rules[0].Priority)
+		assert.Equal(t, 20, rules.Rules[1].Priority)
+	})
+
+	t.Run("Should match a rule when all of its conditions match", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "http://api.example.com:9000/api/v1/users", nil)
+
+		assert.Same(t, api, rules.Match(r))
+	})
+
+	t.Run("Should not match a rule when one of its conditions does not match", func(t *testing.T) {
+		r := httptest.NewRequest("DELETE", "http://api.example.com/api/v1/users", nil)
+
+		assert.Same(t, fallback, rules.Match(r))
+	})
+
+	t.Run("Should match a path prefix", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "http://localhost/static/app.js", nil)
+
+		assert.Same(t, static, rules.Match(r))
+	})
+
+	t.Run("Should fall back to the default action when no rule matches", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "http://localhost/", nil)
+
+		assert.Same(t, fallback, rules.Match(r))
+	})
+}
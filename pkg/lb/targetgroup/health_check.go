@@ -3,30 +3,39 @@ package targetgroup
 import "net/http"
 
 type HealthCheckConfig struct {
+	Type             string
 	Interval         int
 	Timeout          int
 	FailureThreshold int
 	HealthyThreshold int
 	Path             string
+	Scheme           string
+	GrpcService      string
 	HttpClient       *http.Client
 }
 
 type HealthCheckConfigParams struct {
+	Type             string `default:"http"`
 	IntervalInSec    int
 	TimeoutInSec     int
 	FailureThreshold int
 	HealthyThreshold int
 	Path             string `default:"/health"`
+	Scheme           string `default:"http"`
+	GrpcService      string
 	HttpClient       *http.Client
 }
 
 func NewHealthCheckConfig(params HealthCheckConfigParams) *HealthCheckConfig {
 	return &HealthCheckConfig{
+		Type:             params.Type,
 		Interval:         params.IntervalInSec,
 		Timeout:          params.TimeoutInSec,
 		FailureThreshold: params.FailureThreshold,
 		HealthyThreshold: params.HealthyThreshold,
 		Path:             params.Path,
+		Scheme:           params.Scheme,
+		GrpcService:      params.GrpcService,
 		HttpClient:       params.HttpClient,
 	}
 }
@@ -1,77 +1,311 @@
 package targetgroup
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"math"
+	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/joaosczip/go-lb/internal/grpc"
 )
 
 type Target struct {
-	Host    string
-	Port    int
-	Healthy bool
-	mux     sync.RWMutex
+	Host string
+	Port int
+	// Weight is the share of traffic the target gets relative to the other
+	// targets of its group, for the algorithms that honour weights.
+	Weight int
+	// Priority is the failover tier of the target, 0 being the primary one.
+	Priority int
+	// Zone is the availability zone of the target, if known.
+	Zone string
+	// SlowStart is how long the weight of the target ramps up after it
+	// becomes healthy; zero disables the ramp.
+	SlowStart time.Duration
+	// SlowStartAggression shapes the ramp: 1 is linear, higher values ramp
+	// up faster at first.
+	SlowStartAggression float64
+	// MaxUpgradedConns caps the upgraded (WebSocket, ...) connections the
+	// target holds at once; zero means no cap.
+	MaxUpgradedConns int64
+	upgradedConns    atomic.Int64
+	healthy          bool
+	healthySince     time.Time
+	// healthGenerations are incremented whenever the target changes health.
+	healthGenerations []*atomic.Uint64
+	mux               sync.RWMutex
 }
 
 func NewTarget(host string, port int) *Target {
 	return &Target{
-		Host: host,
-		Port: port,
+		Host:   host,
+		Port:   port,
+		Weight: 1,
+	}
+}
+
+// EffectiveWeight returns the weight algorithms should use for the target,
+// which is never below 1.
+func (t *Target) EffectiveWeight() int {
+	if t.Weight < 1 {
+		return 1
+	}
+
+	return t.Weight
+}
+
+// slowStartMinFactor keeps ramping targets from starving, as a weight of
+// zero would never let them warm up.
+const slowStartMinFactor = 0.1
+
+// SlowStartFactor returns the share, between 0.1 and 1, of its weight the
+// target gets at now while it ramps up after becoming healthy.
+func (t *Target) SlowStartFactor(now time.Time) float64 {
+	if t.SlowStart <= 0 {
+		return 1
+	}
+
+	healthySince := t.HealthySince()
+	elapsed := now.Sub(healthySince)
+
+	if healthySince.IsZero() || elapsed >= t.SlowStart {
+		return 1
+	}
+
+	aggression := t.SlowStartAggression
+
+	if aggression <= 0 {
+		aggression = 1
 	}
+
+	factor := math.Pow(float64(elapsed)/float64(t.SlowStart), 1/aggression)
+
+	return max(slowStartMinFactor, factor)
 }
 
-func (t *Target) setHealthy(healthy bool) {
+// SlowStartWeight returns the effective weight of the target scaled by its
+// slow start ramp.
+func (t *Target) SlowStartWeight(now time.Time) float64 {
+	return float64(t.EffectiveWeight()) * t.SlowStartFactor(now)
+}
+
+// WatchHealth makes the target increment generation whenever it changes
+// health, so that state derived from the health of a set of targets sharing
+// generation is known to be stale from one atomic load.
+func (t *Target) WatchHealth(generation *atomic.Uint64) {
 	t.mux.Lock()
 	defer t.mux.Unlock()
-	t.Healthy = healthy
+
+	t.healthGenerations = append(t.healthGenerations, generation)
+}
+
+func (t *Target) SetHealthy(healthy bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if healthy == t.healthy {
+		return
+	}
+
+	if healthy {
+		t.healthySince = time.Now()
+	}
+
+	t.healthy = healthy
+
+	for _, generation := range t.healthGenerations {
+		generation.Add(1)
+	}
+}
+
+// HealthySince returns when the target last became healthy through
+// SetHealthy, or the zero time if it never did.
+func (t *Target) HealthySince() time.Time {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	return t.healthySince
 }
 
 func (t *Target) IsHealthy() bool {
 	t.mux.RLock()
 	defer t.mux.RUnlock()
-	return t.Healthy
+	return t.healthy
+}
+
+// AcquireUpgradedConn reserves a slot for an upgraded connection, reporting
+// false when the target already holds MaxUpgradedConns of them.
+func (t *Target) AcquireUpgradedConn() bool {
+	for {
+		current := t.upgradedConns.Load()
+
+		if t.MaxUpgradedConns > 0 && current >= t.MaxUpgradedConns {
+			return false
+		}
+
+		if t.upgradedConns.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
+func (t *Target) ReleaseUpgradedConn() {
+	t.upgradedConns.Add(-1)
+}
+
+// UpgradedConns returns how many upgraded connections the target holds.
+func (t *Target) UpgradedConns() int64 {
+	return t.upgradedConns.Load()
+}
+
+func (t *Target) checkHttp(ctx context.Context, hc HealthCheckConfig) error {
+	scheme := hc.Scheme
+
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	healthCheckUrl := fmt.Sprintf("%s://%s:%d%s", scheme, t.Host, t.Port, hc.Path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthCheckUrl, nil)
+
+	if err != nil {
+		return fmt.Errorf("could not create request: %v", err)
+	}
+
+	res, err := hc.HttpClient.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// checkGrpc probes the target with the gRPC health checking protocol, which
+// requires HttpClient to speak HTTP/2 to the target.
+func (t *Target) checkGrpc(ctx context.Context, hc HealthCheckConfig) error {
+	scheme := hc.Scheme
+
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	healthCheckUrl := fmt.Sprintf("%s://%s:%d%s", scheme, t.Host, t.Port, grpc.HealthCheckPath)
+	body := grpc.EncodeHealthCheckRequest(hc.GrpcService)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, healthCheckUrl, bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("could not create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	res, err := hc.HttpClient.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	frame, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return fmt.Errorf("could not read response: %v", err)
+	}
+
+	// Failures are reported in the trailers, or in the headers of a
+	// trailers-only response.
+	grpcStatus := res.Trailer.Get("Grpc-Status")
+
+	if grpcStatus == "" {
+		grpcStatus = res.Header.Get("Grpc-Status")
+	}
+
+	if grpcStatus != "0" {
+		return fmt.Errorf("unexpected grpc status %q", grpcStatus)
+	}
+
+	servingStatus, err := grpc.DecodeHealthCheckResponse(frame)
+
+	if err != nil {
+		return fmt.Errorf("could not decode response: %v", err)
+	}
+
+	if servingStatus != grpc.Serving {
+		return fmt.Errorf("unexpected serving status %d", servingStatus)
+	}
+
+	return nil
+}
+
+func (t *Target) checkTcp(ctx context.Context) error {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", t.Host, t.Port))
+
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func (t *Target) check(hc HealthCheckConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(hc.Timeout)*time.Second)
+	defer cancel()
+
+	switch hc.Type {
+	case "tcp":
+		return t.checkTcp(ctx)
+	case "grpc":
+		return t.checkGrpc(ctx, hc)
+	}
+
+	return t.checkHttp(ctx, hc)
 }
 
 func (t *Target) healthCheck(hc HealthCheckConfig) {
 	ticker := time.NewTicker(time.Duration(hc.Interval) * time.Second)
 	defer ticker.Stop()
 
-	healthCheckUrl := fmt.Sprintf("http://%s:%d%s", t.Host, t.Port, hc.Path)
 	failures := 0
 	succeeded := 0
 
 	for {
 		<-ticker.C
 
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(hc.Timeout)*time.Second)
-
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthCheckUrl, nil)
-
-		if err != nil {
-			log.Fatalf("could not create request: %v", err)
-		}
-
-		res, err := hc.HttpClient.Do(req)
-
-		cancel()
+		err := t.check(hc)
 
 		if err == nil {
-			if res.StatusCode == http.StatusOK {
-				failures = 0
-				succeeded++
-				fmt.Printf("health check passed for target %s:%d, %d, %d\n", t.Host, t.Port, succeeded, hc.HealthyThreshold)
-
-				if !t.IsHealthy() && succeeded >= hc.HealthyThreshold {
-					fmt.Printf("target %s:%d is healthy\n", t.Host, t.Port)
-					t.setHealthy(true)
-				}
-
-				res.Body.Close()
-				continue
+			failures = 0
+			succeeded++
+			fmt.Printf("health check passed for target %s:%d, %d, %d\n", t.Host, t.Port, succeeded, hc.HealthyThreshold)
+
+			if !t.IsHealthy() && succeeded >= hc.HealthyThreshold {
+				fmt.Printf("target %s:%d is healthy\n", t.Host, t.Port)
+				t.SetHealthy(true)
 			}
+
+			continue
 		}
 
 		fmt.Printf("health check failed for target %s:%d: %v\n", t.Host, t.Port, err)
@@ -80,7 +314,7 @@ func (t *Target) healthCheck(hc HealthCheckConfig) {
 
 		if failures > hc.FailureThreshold {
 			fmt.Printf("target %s:%d is unhealthy\n", t.Host, t.Port)
-			t.setHealthy(false)
+			t.SetHealthy(false)
 		}
 	}
 }
@@ -1,21 +1,34 @@
 package targetgroup
 
-import lb "github.com/joaosczip/go-lb/pkg/lb/algorithms"
+import "net/http"
+
+// Algorithm picks a target of the group for each request. It lives here
+// rather than in pkg/lb/algorithms, which needs the targets to build
+// algorithms, to keep both packages free of an import cycle.
+type Algorithm interface {
+	Handle(w http.ResponseWriter, r *http.Request) error
+}
 
 type TargetGroup struct {
+	Name              string
+	Protocol          string
 	Targets           []*Target
 	HealthCheckConfig *HealthCheckConfig
-	Algorithm         lb.Algorithm
+	Algorithm         Algorithm
 }
 
 type NewTargetGroupParams struct {
+	Name              string
+	Protocol          string
 	Targets           []*Target
 	HealthCheckConfig *HealthCheckConfig
-	Algorithm         lb.Algorithm
+	Algorithm         Algorithm
 }
 
 func NewTargetGroup(params NewTargetGroupParams) *TargetGroup {
 	tg := &TargetGroup{
+		Name:              params.Name,
+		Protocol:          params.Protocol,
 		Targets:           params.Targets,
 		HealthCheckConfig: params.HealthCheckConfig,
 		Algorithm:         params.Algorithm,